@@ -0,0 +1,115 @@
+package main
+
+import (
+	"changeme/stacktrace"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The control API on Settings.HTTPAddress can be reached by any local process
+// and, through DNS rebinding, by any web page. Every request must therefore
+// carry the token from controlTokenFileName in the data directory, either as
+// "Authorization: Bearer <token>" or in the controlTokenCookieName cookie,
+// its Host header must name the loopback interface and, if it comes from a
+// browser, its Origin must be the API itself.
+//
+// The embedded frontend does not go through the control API, it calls the
+// backend through the Wails asset server, and can get the token with
+// ControlAPIToken to hand it to other tools.
+const (
+	controlTokenFileName   = "controltoken"
+	controlTokenCookieName = "browserautomate_token"
+)
+
+// loadControlToken reads the control API token from the data directory,
+// creating a random one on first use.
+func loadControlToken(dataDirectory string) (string, error) {
+	filePath := filepath.Join(dataDirectory, controlTokenFileName)
+	b, err := os.ReadFile(filePath)
+	if err == nil && len(strings.TrimSpace(string(b))) >= 32 {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", stacktrace.New(err)
+	}
+	var token [32]byte
+	_, err = rand.Read(token[:])
+	if err != nil {
+		return "", stacktrace.New(err)
+	}
+	err = os.MkdirAll(dataDirectory, 0755)
+	if err != nil {
+		return "", stacktrace.New(err)
+	}
+	// Anyone who can read the token can control the browser.
+	err = os.WriteFile(filePath, []byte(hex.EncodeToString(token[:])+"\n"), 0600)
+	if err != nil {
+		return "", stacktrace.New(err)
+	}
+	return hex.EncodeToString(token[:]), nil
+}
+
+// ControlAPIToken returns the token that clients of the control API must
+// send.
+func (backend *Backend) ControlAPIToken() string {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	return backend.ControlToken
+}
+
+// controlAPI wraps the backend's HTTP handler with the checks described
+// above for serving it on httpAddress.
+func (backend *Backend) controlAPI(httpAddress string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpAddress)
+	allowedHosts := []string{"localhost:" + port, "127.0.0.1:" + port, "[::1]:" + port}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostAllowed := false
+		for _, allowedHost := range allowedHosts {
+			if strings.EqualFold(r.Host, allowedHost) {
+				hostAllowed = true
+				break
+			}
+		}
+		if !hostAllowed {
+			http.Error(w, "InvalidHost", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			originURL, err := url.Parse(origin)
+			if err != nil || originURL.Scheme != "http" || !strings.EqualFold(originURL.Host, r.Host) {
+				http.Error(w, "InvalidOrigin", http.StatusForbidden)
+				return
+			}
+		}
+		backend.Mutex.Lock()
+		controlToken := backend.ControlToken
+		backend.Mutex.Unlock()
+		if controlToken == "" {
+			// The backend failed to start before the token was loaded.
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = ""
+			if cookie, err := r.Cookie(controlTokenCookieName); err == nil {
+				token = cookie.Value
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(controlToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	})
+}
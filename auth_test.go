@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestControlAPI(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	backend := &Backend{
+		Startup:      StartupStatus{State: StartupStateReady},
+		ControlToken: token,
+	}
+	handler := backend.controlAPI("localhost:9246")
+	tests := []struct {
+		name       string
+		host       string
+		origin     string
+		header     string
+		cookie     string
+		statusCode int
+	}{
+		{name: "missing token", host: "localhost:9246", statusCode: http.StatusUnauthorized},
+		{name: "wrong token", host: "localhost:9246", header: "Bearer " + token[1:] + "0", statusCode: http.StatusUnauthorized},
+		{name: "wrong cookie", host: "localhost:9246", cookie: "wrong", statusCode: http.StatusUnauthorized},
+		{name: "token without Bearer", host: "localhost:9246", header: token, statusCode: http.StatusUnauthorized},
+		{name: "bearer token", host: "localhost:9246", header: "Bearer " + token, statusCode: http.StatusOK},
+		{name: "cookie", host: "127.0.0.1:9246", cookie: token, statusCode: http.StatusOK},
+		{name: "IPv6 loopback", host: "[::1]:9246", header: "Bearer " + token, statusCode: http.StatusOK},
+		{name: "foreign host", host: "attacker.example:9246", header: "Bearer " + token, statusCode: http.StatusForbidden},
+		{name: "other port", host: "localhost:80", header: "Bearer " + token, statusCode: http.StatusForbidden},
+		{name: "same origin", host: "localhost:9246", origin: "http://localhost:9246", cookie: token, statusCode: http.StatusOK},
+		{name: "foreign origin", host: "localhost:9246", origin: "http://attacker.example", cookie: token, statusCode: http.StatusForbidden},
+		{name: "null origin", host: "localhost:9246", origin: "null", cookie: token, statusCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/processes/", nil)
+			r.Host = tt.host
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: controlTokenCookieName, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.statusCode {
+				t.Errorf("got status %d, want %d (body %q)", w.Code, tt.statusCode, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestControlAPITokenNotLoaded(t *testing.T) {
+	backend := &Backend{}
+	r := httptest.NewRequest("GET", "/v1/processes/", nil)
+	r.Host = "localhost:9246"
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	backend.controlAPI("localhost:9246").ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestLoadControlToken(t *testing.T) {
+	dataDirectory := filepath.Join(t.TempDir(), "data")
+	token, err := loadControlToken(dataDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 {
+		t.Errorf("got token %q, want 64 hex digits", token)
+	}
+	fileInfo, err := os.Stat(filepath.Join(dataDirectory, controlTokenFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fileInfo.Mode().Perm() != 0600 {
+		t.Errorf("got permissions %v, want 0600", fileInfo.Mode().Perm())
+	}
+	token2, err := loadControlToken(dataDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token2 != token {
+		t.Errorf("got token %q on the second load, want %q", token2, token)
+	}
+}
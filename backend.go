@@ -3,16 +3,12 @@ package main
 import (
 	"changeme/stacktrace"
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
-	"os/exec"
-	"runtime"
-	"strconv"
-	"strings"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/playwright-community/playwright-go"
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -24,16 +20,33 @@ func init() {
 }
 
 type Backend struct {
-	App                    *application.App
-	Playwright             *playwright.Playwright
-	PlaywrightDriver       *playwright.PlaywrightDriver
-	PlaywrightRunOptions   *playwright.RunOptions
-	ChromeProfileDirectory string
-	Browser                playwright.Browser
-	Sequence               atomic.Int64
-	Mutex                  sync.Mutex
-	Windows                map[string]*application.WebviewWindow
-	Pages                  map[int64]playwright.Page
+	App                  *application.App
+	Playwright           *playwright.Playwright
+	PlaywrightDriver     *playwright.PlaywrightDriver
+	PlaywrightRunOptions *playwright.RunOptions
+	ProfilesDirectory    string
+	BrowserLauncherFile  string
+	DriverChecksumsFile  string
+	DriversDirectory     string
+	DownloadsDirectory   string
+	FlowsDirectory       string
+	Sessions             map[string]*BrowserSession
+	OpeningProfiles      map[string]bool
+	Sequence             atomic.Int64
+	Mutex                sync.Mutex
+	Windows              map[string]*application.WebviewWindow
+	Pages                map[int64]playwright.Page
+	TabDetails           map[int64]*TabDetail
+	DriverInstall        *DriverInstall
+	DriverOperation      string
+	FlowRuns             map[string]*FlowRun
+	Processes            map[string]*Process
+	Startup              StartupStatus
+	Settings             Settings
+	SettingsEnv          SettingsLayer
+	SettingsFlags        SettingsLayer
+	ControlToken         string
+	Events               EventHub
 }
 
 type ProcessUpdate struct {
@@ -87,7 +100,7 @@ func (backend *Backend) CreateWindow(options WebviewWindowOptions) error {
 	window.OnWindowEvent(events.Common.WindowClosing, func(event *application.WindowEvent) {
 		backend.Mutex.Lock()
 		defer backend.Mutex.Unlock()
-		backend.App.Event.EmitEvent(&application.CustomEvent{
+		backend.emitEvent(&application.CustomEvent{
 			Name:   "WindowClosed",
 			Sender: name,
 		})
@@ -150,6 +163,9 @@ func (backend *Backend) FocusWindow(name string) error {
 }
 
 func (backend *Backend) StartPlaywright() error {
+	if !backend.ready() {
+		return fmt.Errorf("the backend failed to start, see the recovery window")
+	}
 	if backend.Playwright == nil {
 		var err error
 		backend.Playwright, err = playwright.Run(backend.PlaywrightRunOptions)
@@ -160,73 +176,172 @@ func (backend *Backend) StartPlaywright() error {
 	return nil
 }
 
-func (backend *Backend) OpenBrowser() error {
-	if backend.Browser == nil || !backend.Browser.IsConnected() {
-		var err error
-		backend.Browser, err = backend.Playwright.Chromium.ConnectOverCDP("http://localhost:9222")
+// OpenBrowser opens a browser for a profile, either by attaching to (and if
+// necessary launching) a user-visible browser over CDP or by launching
+// Playwright's own Chromium, Firefox or WebKit build. Browsers for different
+// profiles can be open at the same time.
+func (backend *Backend) OpenBrowser(ctx context.Context, options OpenBrowserOptions) error {
+	return backend.openBrowser(ctx, windowName(ctx), options)
+}
+
+// openBrowser opens the browser for options.ProfileName, reporting progress
+// to the status bar of windowName. Waiting for a launched browser stops when
+// ctx is cancelled.
+func (backend *Backend) openBrowser(ctx context.Context, windowName string, options OpenBrowserOptions) error {
+	profileName := options.ProfileName
+	if profileName == "" {
+		profileName = DefaultProfileName
+	}
+	profileDirectory, err := backend.profileDirectory(profileName)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(profileDirectory, 0755)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	launchMode := options.LaunchMode
+	if launchMode == "" {
+		launchMode = LaunchModeCDP
+	}
+	browserName := options.BrowserName
+	if browserName == "" {
+		browserName = BrowserChromium
+	}
+	if launchMode == LaunchModeCDP && browserName != BrowserChromium {
+		return fmt.Errorf("only Chromium-based browsers can be attached over CDP, use LaunchModePersistent for %s", browserName)
+	}
+	backend.Mutex.Lock()
+	session := backend.Sessions[profileName]
+	if session.IsConnected() {
+		backend.Mutex.Unlock()
+		if session.LaunchMode != launchMode || session.BrowserName != browserName || (launchMode == LaunchModePersistent && session.Headless != options.Headless) {
+			return fmt.Errorf("profile %q is already open with %s in %s mode, close it first", profileName, session.BrowserName, session.LaunchMode)
+		}
+		return nil
+	}
+	// Only one call at a time may launch or attach to a profile's browser.
+	if backend.OpeningProfiles[profileName] {
+		backend.Mutex.Unlock()
+		return fmt.Errorf("profile %q is already being opened or changed", profileName)
+	}
+	backend.OpeningProfiles[profileName] = true
+	backend.Mutex.Unlock()
+	defer func() {
+		backend.Mutex.Lock()
+		delete(backend.OpeningProfiles, profileName)
+		backend.Mutex.Unlock()
+	}()
+	process := backend.beginProcess(ctx, windowName, fmt.Sprintf("Opening profile %q", profileName), 4)
+	err = backend.launchSession(process, profileName, profileDirectory, launchMode, browserName, options)
+	if err != nil {
+		process.Fail(err)
+		return err
+	}
+	process.Finish(fmt.Sprintf("Opened profile %q", profileName))
+	return nil
+}
+
+// launchSession attaches to or launches the browser for a profile and starts
+// tracking and supervising it.
+func (backend *Backend) launchSession(process *Process, profileName, profileDirectory, launchMode, browserName string, options OpenBrowserOptions) error {
+	var session *BrowserSession
+	switch launchMode {
+	case LaunchModeCDP:
+		browser, err := backend.attachOverCDP(process, profileDirectory)
 		if err != nil {
-			var playwrightErr *playwright.Error
-			if !errors.As(err, &playwrightErr) || !strings.Contains(playwrightErr.Message, "ECONNREFUSED") {
-				return fmt.Errorf("unexpected error when connecting via Chrome DevTools Protocol: %w", err)
-			}
-			var cmd *exec.Cmd
-			switch runtime.GOOS {
-			case "windows":
-				cmd = exec.Command("pwsh.exe", "-command", fmt.Sprintf(`Start-Process "C:\Program Files\Google\Chrome\Application\chrome.exe" -ArgumentList --remote-debugging-port=9222, --user-data-dir=%s, https://www.google.com`, strconv.Quote(backend.ChromeProfileDirectory)))
-			case "darwin":
-				cmd = exec.Command("open", "-a", "Google Chrome", "--args", "--remote-debugging-port=9222", "--user-data-dir="+backend.ChromeProfileDirectory, "https://www.google.com")
-			default:
-				return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
-			}
-			fmt.Printf("running %s\n", cmd.String())
-			err = cmd.Run()
-			if err != nil {
-				return fmt.Errorf("failed to start Google Chrome: %s: %w", cmd.String(), err)
-			}
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-			waitForCDP(ctx, "http://127.0.0.1:9222")
-			backend.Browser, err = backend.Playwright.Chromium.ConnectOverCDP("http://127.0.0.1:9222")
+			return err
+		}
+		if len(browser.Contexts()) == 0 {
+			_, err := browser.NewContext()
 			if err != nil {
-				return fmt.Errorf("error connecting via Chrome DevTools Protocol: %w", err)
+				return stacktrace.New(err)
 			}
 		}
-	}
-	browserContexts := backend.Browser.Contexts()
-	if len(browserContexts) == 0 {
-		_, err := backend.Browser.NewContext()
+		session = newCDPBrowserSession(profileName, browser)
+	case LaunchModePersistent:
+		// Not reserveProfiles, openBrowser has reserved the profile.
+		if backend.profileBrowserRunning(profileName) {
+			return fmt.Errorf("profile %q is in use by another browser, close it first", profileName)
+		}
+		browserType, err := backend.playwrightBrowserType(browserName)
 		if err != nil {
-			return stacktrace.New(err)
+			return err
+		}
+		// Firefox and WebKit profiles are not compatible with Chrome's
+		// user data directory, keep them in a subdirectory.
+		userDataDirectory := profileDirectory
+		if browserName != BrowserChromium {
+			userDataDirectory = filepath.Join(profileDirectory, "playwright-"+browserName)
 		}
+		process.Step(fmt.Sprintf("Launching Playwright %s", browserName))
+		browserContext, err := browserType.LaunchPersistentContext(userDataDirectory, playwright.BrowserTypeLaunchPersistentContextOptions{
+			Headless: playwright.Bool(options.Headless),
+		})
+		if err != nil {
+			return fmt.Errorf("launching Playwright %s (is it installed?): %w", browserName, err)
+		}
+		session = newPersistentBrowserSession(profileName, browserName, options.Headless, browserContext)
+	default:
+		return fmt.Errorf("invalid launch mode %q", launchMode)
 	}
+	backend.Mutex.Lock()
+	backend.Sessions[profileName] = session
+	backend.Mutex.Unlock()
+	process.Step("Tracking tabs")
+	backend.trackSessionPages(session)
+	go backend.superviseSession(session, process.windowName, options)
+	backend.emitBrowserStatus(BrowserStatus{
+		ProfileName: profileName,
+		Status:      "connected",
+	})
 	return nil
 }
 
-func waitForCDP(ctx context.Context, endpoint string) error {
-	client := &http.Client{Timeout: 500 * time.Millisecond}
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
-	url := endpoint + "/json/version"
-	for {
-		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		resp, err := client.Do(req)
-		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				return nil
-			}
-		}
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("cdp not ready: %w", ctx.Err())
-		case <-ticker.C:
-		}
+// CloseBrowser closes the browser session of a profile without reconnecting
+// it. Browsers launched with LaunchModePersistent are closed, CDP sessions are
+// only disconnected and leave the browser running.
+func (backend *Backend) CloseBrowser(profileName string) error {
+	if profileName == "" {
+		profileName = DefaultProfileName
+	}
+	backend.Mutex.Lock()
+	session, ok := backend.Sessions[profileName]
+	if ok {
+		delete(backend.Sessions, profileName)
+	}
+	backend.Mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("profile %q is not open", profileName)
+	}
+	// Mark the session as closing before it disconnects so that
+	// superviseSession does not reconnect it.
+	session.closing.Store(true)
+	backend.untrackSessionPages(session)
+	// A session waiting to be reconnected has nothing left to close.
+	connected := session.IsConnected()
+	err := session.Close()
+	if err != nil && connected {
+		return stacktrace.New(err)
 	}
+	return nil
 }
 
 func (backend *Backend) Close() error {
-	backend.Browser.Close()
+	// Only take what needs closing under the lock. Closing a browser waits
+	// for Playwright's dispatcher, whose event handlers (e.g. page close)
+	// lock backend.Mutex themselves, and closing a window runs its
+	// WindowClosing handler.
 	backend.Mutex.Lock()
+	for _, process := range backend.Processes {
+		process.cancel()
+	}
+	sessions := make([]*BrowserSession, 0, len(backend.Sessions))
+	for _, session := range backend.Sessions {
+		sessions = append(sessions, session)
+	}
+	clear(backend.Sessions)
+	var browserContexts []playwright.BrowserContext
 	seen := make(map[playwright.BrowserContext]struct{})
 	for _, page := range backend.Pages {
 		browserContext := page.Context()
@@ -234,14 +349,25 @@ func (backend *Backend) Close() error {
 			continue
 		}
 		seen[browserContext] = struct{}{}
-		browserContext.Close()
+		browserContexts = append(browserContexts, browserContext)
 	}
 	clear(backend.Pages)
+	clear(backend.TabDetails)
+	windows := make([]*application.WebviewWindow, 0, len(backend.Windows))
 	for _, window := range backend.Windows {
-		window.Close()
+		windows = append(windows, window)
 	}
 	clear(backend.Windows)
 	backend.Mutex.Unlock()
+	for _, session := range sessions {
+		session.Close()
+	}
+	for _, browserContext := range browserContexts {
+		browserContext.Close()
+	}
+	for _, window := range windows {
+		window.Close()
+	}
 	return nil
 }
 
@@ -401,3 +527,25 @@ type MessageDialogOptions struct {
 	Title      string
 	Message    string
 }
+
+type OpenBrowserOptions struct {
+	// ProfileName is the profile to open the browser with.
+	// Default: "default"
+	ProfileName string
+
+	// LaunchMode is how the browser is acquired.
+	// Default: LaunchModeCDP
+	LaunchMode string // LaunchModeCDP|LaunchModePersistent
+
+	// BrowserName is the browser engine to use. Only BrowserChromium can be
+	// used with LaunchModeCDP.
+	// Default: BrowserChromium
+	BrowserName string // BrowserChromium|BrowserFirefox|BrowserWebKit
+
+	// Headless hides the browser window. Only applies to LaunchModePersistent.
+	Headless bool
+
+	// AutoReconnect reopens the browser with backoff if it is closed or the
+	// connection to it is lost.
+	AutoReconnect bool
+}
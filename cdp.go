@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"changeme/stacktrace"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// chromeProfileConfigName is the name of the file in a Chrome user data
+// directory where we keep our own settings for that profile.
+const chromeProfileConfigName = "ba2.json"
+
+type chromeProfileConfig struct {
+	// CDPPort is the remote debugging port the user configured for the
+	// profile. If zero, a port is picked automatically.
+	CDPPort int `json:"cdpPort"`
+}
+
+func readChromeProfileConfig(profileDirectory string) (chromeProfileConfig, error) {
+	var config chromeProfileConfig
+	b, err := os.ReadFile(filepath.Join(profileDirectory, chromeProfileConfigName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return config, nil
+		}
+		return config, stacktrace.New(err)
+	}
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", filepath.Join(profileDirectory, chromeProfileConfigName), err)
+	}
+	return config, nil
+}
+
+func writeChromeProfileConfig(profileDirectory string, config chromeProfileConfig) error {
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	err = os.WriteFile(filepath.Join(profileDirectory, chromeProfileConfigName), b, 0644)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// readDevToolsActivePort reads the DevToolsActivePort file that Chrome writes
+// into its user data directory when launched with --remote-debugging-port. It
+// contains the port Chrome is listening on and the path of the browser's
+// websocket endpoint (/devtools/browser/<id>), which is unique per launch.
+func readDevToolsActivePort(profileDirectory string) (port int, browserPath string, err error) {
+	b, err := os.ReadFile(filepath.Join(profileDirectory, "DevToolsActivePort"))
+	if err != nil {
+		return 0, "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	if scanner.Scan() {
+		port, err = strconv.Atoi(scanner.Text())
+		if err != nil {
+			return 0, "", fmt.Errorf("DevToolsActivePort: invalid port: %w", err)
+		}
+	}
+	if scanner.Scan() {
+		browserPath = scanner.Text()
+	}
+	return port, browserPath, nil
+}
+
+// freePort asks the OS for a free TCP port on the loopback interface.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, stacktrace.New(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// CDPPort returns the remote debugging port configured for a profile. Zero
+// means the port is picked automatically.
+func (backend *Backend) CDPPort(profileName string) (int, error) {
+	profileDirectory, err := backend.profileDirectory(profileName)
+	if err != nil {
+		return 0, err
+	}
+	config, err := readChromeProfileConfig(profileDirectory)
+	if err != nil {
+		return 0, err
+	}
+	return config.CDPPort, nil
+}
+
+// SetCDPPort configures the remote debugging port for a profile. Pass zero to
+// pick a free port automatically.
+func (backend *Backend) SetCDPPort(profileName string, port int) error {
+	if port != 0 && (port < 1024 || port > 65535) {
+		return fmt.Errorf("invalid port %d: must be between 1024 and 65535, or 0 to pick automatically", port)
+	}
+	profileDirectory, err := backend.profileDirectory(profileName)
+	if err != nil {
+		return err
+	}
+	if port != 0 {
+		profiles, err := backend.ListProfiles()
+		if err != nil {
+			return err
+		}
+		for _, profile := range profiles {
+			if profile.Directory != profileDirectory && profile.CDPPort == port {
+				return fmt.Errorf("port %d is already used by profile %q", port, profile.Name)
+			}
+		}
+	}
+	config, err := readChromeProfileConfig(profileDirectory)
+	if err != nil {
+		return err
+	}
+	config.CDPPort = port
+	return writeChromeProfileConfig(profileDirectory, config)
+}
+
+// CDPMismatchError is returned when the browser listening on a remote
+// debugging port is not the one launched with our user data directory.
+type CDPMismatchError struct {
+	Port    int
+	Browser string
+}
+
+func (e *CDPMismatchError) Error() string {
+	return fmt.Sprintf("port %d is in use by another browser (%s) which was not launched with our profile directory", e.Port, e.Browser)
+}
+
+// verifyCDP checks that the browser listening on port is the one launched
+// with profileDirectory by comparing the websocket endpoint reported by
+// /json/version with the one Chrome recorded in DevToolsActivePort.
+func verifyCDP(ctx context.Context, port int, profileDirectory string) error {
+	client := &http.Client{Timeout: 500 * time.Millisecond}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+"/json/version", nil)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /json/version: %s", resp.Status)
+	}
+	var version struct {
+		Browser              string `json:"Browser"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&version)
+	if err != nil {
+		return fmt.Errorf("GET /json/version: %w", err)
+	}
+	webSocketDebuggerURL, err := url.Parse(version.WebSocketDebuggerURL)
+	if err != nil {
+		return fmt.Errorf("GET /json/version: %w", err)
+	}
+	activePort, browserPath, err := readDevToolsActivePort(profileDirectory)
+	if err != nil || activePort != port || browserPath != webSocketDebuggerURL.Path {
+		return &CDPMismatchError{Port: port, Browser: version.Browser}
+	}
+	return nil
+}
+
+// waitForCDP waits until the browser launched with profileDirectory is
+// accepting connections on port.
+func waitForCDP(ctx context.Context, port int, profileDirectory string) error {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		err := verifyCDP(ctx, port, profileDirectory)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cdp not ready: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// attachOverCDP connects to the browser running with profileDirectory,
+// launching the selected browser with a remote debugging port if it is not
+// running yet. Progress is reported to process.
+func (backend *Backend) attachOverCDP(process *Process, profileDirectory string) (playwright.Browser, error) {
+	config, err := readChromeProfileConfig(profileDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if config.CDPPort == 0 {
+		config.CDPPort = backend.settings().DefaultCDPPort
+	}
+	port := config.CDPPort
+	if port == 0 {
+		// If the browser is already running, Chrome will have recorded
+		// the port it picked in the profile directory.
+		port, _, _ = readDevToolsActivePort(profileDirectory)
+	}
+	needLaunch := port == 0
+	if !needLaunch {
+		ctx, cancel := context.WithTimeout(process.Context(), 2*time.Second)
+		err = verifyCDP(ctx, port, profileDirectory)
+		cancel()
+		if err != nil {
+			var mismatchErr *CDPMismatchError
+			if errors.As(err, &mismatchErr) && config.CDPPort != 0 {
+				return nil, fmt.Errorf("%w: configure a different CDP port", err)
+			}
+			needLaunch = true
+		}
+	}
+	if needLaunch {
+		err = process.Context().Err()
+		if err != nil {
+			return nil, err
+		}
+		if config.CDPPort == 0 {
+			port, err = freePort()
+			if err != nil {
+				return nil, err
+			}
+		}
+		browserLauncher, err := backend.SelectedBrowserLauncher()
+		if err != nil {
+			return nil, err
+		}
+		process.Step(fmt.Sprintf("Launching %s", browserLauncher.Name))
+		cmd := exec.Command(browserLauncher.ExecutablePath, "--remote-debugging-port="+strconv.Itoa(port), "--user-data-dir="+profileDirectory, "https://www.google.com")
+		slog.Info("launching browser", "command", cmd.String())
+		// Start the browser in the background and reap it when it exits.
+		err = cmd.Start()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start %s: %s: %w", browserLauncher.Name, cmd.String(), err)
+		}
+		go cmd.Wait()
+		process.Step(fmt.Sprintf("Waiting for %s to listen on port %d", browserLauncher.Name, port))
+		ctx, cancel := context.WithTimeout(process.Context(), 10*time.Second)
+		defer cancel()
+		err = waitForCDP(ctx, port, profileDirectory)
+		if err != nil {
+			if process.Context().Err() != nil {
+				return nil, process.Context().Err()
+			}
+			return nil, fmt.Errorf("%s did not start listening on port %d (is it already running with the same profile?): %w", browserLauncher.Name, port, err)
+		}
+	}
+	err = process.Context().Err()
+	if err != nil {
+		return nil, err
+	}
+	process.Step(fmt.Sprintf("Connecting to port %d", port))
+	browser, err := backend.Playwright.Chromium.ConnectOverCDP("http://127.0.0.1:" + strconv.Itoa(port))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting via Chrome DevTools Protocol: %w", err)
+	}
+	return browser, nil
+}
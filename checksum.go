@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// driverChecksums are the SHA-256 checksums of the Playwright driver
+// archives, by driver version and platform (as used in the archive name,
+// e.g. "playwright-1.57.0-linux.zip" is version "1.57.0", platform "linux").
+// They are generated with "task checksums:driver" whenever the playwright-go
+// dependency is upgraded. Entries in Backend.DriverChecksumsFile take
+// precedence, so that a new driver version can be verified without
+// rebuilding.
+//
+//go:embed driverchecksums.json
+var driverChecksumsJSON []byte
+
+var driverChecksums = func() map[string]map[string]string {
+	var checksums map[string]map[string]string
+	err := json.Unmarshal(driverChecksumsJSON, &checksums)
+	if err != nil {
+		panic("driverchecksums.json: " + err.Error())
+	}
+	return checksums
+}()
+
+// ChecksumMismatchError is returned when a downloaded file does not match its
+// expected checksum.
+type ChecksumMismatchError struct {
+	FilePath string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: expected sha256 %s, got %s", e.FilePath, e.Expected, e.Actual)
+}
+
+// ChecksumUnknownError is returned when no checksum is known for a driver
+// archive. The archive is only installed if the user explicitly allows
+// installing it unverified.
+type ChecksumUnknownError struct {
+	FileName string
+}
+
+func (e *ChecksumUnknownError) Error() string {
+	return fmt.Sprintf("no checksum known for %s", e.FileName)
+}
+
+// driverChecksum returns the expected SHA-256 checksum (hex encoded) of the
+// driver archive for version and platform, or an empty string if it is not
+// known.
+func (backend *Backend) driverChecksum(version, platform string) (string, error) {
+	if backend.DriverChecksumsFile != "" {
+		b, err := os.ReadFile(backend.DriverChecksumsFile)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		} else {
+			var checksums map[string]map[string]string
+			err = json.Unmarshal(b, &checksums)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", backend.DriverChecksumsFile, err)
+			}
+			if checksum := checksums[version][platform]; checksum != "" {
+				return strings.ToLower(checksum), nil
+			}
+		}
+	}
+	return strings.ToLower(driverChecksums[version][platform]), nil
+}
+
+// sha256File returns the SHA-256 checksum (hex encoded) of a file.
+func sha256File(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyChecksum checks a file against its expected SHA-256 checksum.
+func verifyChecksum(filePath string, expected string) error {
+	actual, err := sha256File(filePath)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return &ChecksumMismatchError{FilePath: filePath, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// verifyDriverArchive checks a driver archive against the checksum manifest
+// and returns the checksum it matched. If no checksum is known it returns a
+// ChecksumUnknownError, unless allowUnverified is set in which case it
+// returns an empty checksum.
+func (backend *Backend) verifyDriverArchive(version, platform, filePath string, allowUnverified bool) (string, error) {
+	checksum, err := backend.driverChecksum(version, platform)
+	if err != nil {
+		return "", fmt.Errorf("reading checksums: %w", err)
+	}
+	if checksum == "" {
+		if allowUnverified {
+			return "", nil
+		}
+		return "", &ChecksumUnknownError{FileName: driverArchiveName(version, platform)}
+	}
+	err = verifyChecksum(filePath, checksum)
+	if err != nil {
+		return "", err
+	}
+	return checksum, nil
+}
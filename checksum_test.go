@@ -0,0 +1,30 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestDriverChecksumsCoverPinnedVersion(t *testing.T) {
+	playwrightDriver, err := playwright.NewDriver(&playwright.RunOptions{
+		DriverDirectory:     t.TempDir(),
+		SkipInstallBrowsers: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := playwrightDriver.Version
+	sha256Hex := regexp.MustCompile(`^[0-9a-f]{64}$`)
+	for _, platform := range driverPlatforms {
+		checksum := driverChecksums[version][platform]
+		if checksum == "" {
+			t.Errorf("driverchecksums.json has no checksum for %s (run task checksums:driver)", driverArchiveName(version, platform))
+			continue
+		}
+		if !sha256Hex.MatchString(checksum) {
+			t.Errorf("driverchecksums.json: %s: invalid sha256 %q", driverArchiveName(version, platform), checksum)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloadStallTimeout is how long a download may go without receiving any
+// data before the attempt is abandoned (and resumed on the next attempt). A
+// variable so that tests can shorten it.
+var downloadStallTimeout = 60 * time.Second
+
+// downloadRetries is the number of passes made over all download URLs before
+// giving up.
+const downloadRetries = 4
+
+// downloadProgressInterval is the minimum time between two progress reports
+// of a download. Every report becomes an event sent to the windows, so
+// reporting every read would flood them. A variable so that tests can
+// change it.
+var downloadProgressInterval = 250 * time.Millisecond
+
+// downloadProgress reports the progress of resumableDownload. Total is -1
+// if the size is not known.
+type downloadProgress func(written, total int64)
+
+// resumableDownload downloads the first URL that works into filePath. The
+// data is written to filePath + ".part" first and renamed into place once
+// complete, so filePath is never left truncated.
+//
+// If a .part file is left over from an earlier attempt, the download resumes
+// from where it left off using a Range request. The ETag (or Last-Modified
+// date) of the response is stored next to the .part file and sent as
+// If-Range, so that a server with a different file restarts from zero
+// instead of appending to stale data.
+//
+// Failed attempts are retried across all URLs with exponential backoff until
+// ctx is cancelled. info is called with a message for every attempt.
+func resumableDownload(ctx context.Context, httpClient *http.Client, urls []string, filePath string, info func(message string), progress downloadProgress) error {
+	backoff := time.Second
+	var errs []error
+	for retry := 0; retry < downloadRetries; retry++ {
+		if retry > 0 {
+			info(fmt.Sprintf("retrying in %s", backoff))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, 30*time.Second)
+		}
+		for _, downloadURL := range urls {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err := downloadAttempt(ctx, httpClient, downloadURL, filePath, info, progress)
+			if err == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			info(fmt.Sprintf("GET %s: %v", downloadURL, err))
+			errs = append(errs, err)
+		}
+	}
+	return fmt.Errorf("failed to download %s after %d attempts: %w", filePath, len(errs), errors.Join(errs...))
+}
+
+// downloadAttempt makes a single attempt at downloading downloadURL, resuming
+// the .part file if possible.
+func downloadAttempt(ctx context.Context, httpClient *http.Client, downloadURL string, filePath string, info func(message string), progress downloadProgress) error {
+	partFilePath := filePath + ".part"
+	validatorFilePath := partFilePath + ".validator"
+	var offset int64
+	var validator string
+	if fileInfo, err := os.Stat(partFilePath); err == nil {
+		offset = fileInfo.Size()
+		b, err := os.ReadFile(validatorFilePath)
+		if err == nil {
+			validator = strings.TrimSpace(string(b))
+		}
+		if validator == "" {
+			// Without a validator we can't tell if the server still has the
+			// same file, start over.
+			offset = 0
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Cancel the attempt if no data arrives for downloadStallTimeout, but
+	// otherwise let slow downloads take as long as they need.
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stallTimer := time.AfterFunc(downloadStallTimeout, cancel)
+	defer stallTimer.Stop()
+
+	req, err := http.NewRequestWithContext(attemptCtx, "GET", downloadURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+		info(fmt.Sprintf("resuming download from %s at %s", downloadURL, HumanReadableFileSize(offset)))
+	} else {
+		info(fmt.Sprintf("attempting to download from %s", downloadURL))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	flag := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Either a fresh download or the server ignored (or rejected the
+		// validator of) our Range request.
+		offset = 0
+		flag |= os.O_TRUNC
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if start != offset {
+			return fmt.Errorf("server resumed at byte %d, expected %d", start, offset)
+		}
+		flag |= os.O_APPEND
+		total = size
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The .part file is larger than the file on the server, it must be
+		// stale.
+		os.Remove(partFilePath)
+		os.Remove(validatorFilePath)
+		return fmt.Errorf("%s", resp.Status)
+	default:
+		return fmt.Errorf("non 200 status code %d (%s)", resp.StatusCode, resp.Status)
+	}
+
+	if validator = resp.Header.Get("ETag"); validator == "" || strings.HasPrefix(validator, "W/") {
+		// Weak ETags can't be used with If-Range.
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator != "" {
+		err = os.WriteFile(validatorFilePath, []byte(validator), 0644)
+	} else {
+		err = os.Remove(validatorFilePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(partFilePath, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info(fmt.Sprintf("downloading from %s", downloadURL))
+	written := offset
+	progress(written, total)
+	lastProgress := time.Now()
+	var buf [32 * 1024]byte
+	for {
+		bytesRead, readErr := resp.Body.Read(buf[:])
+		if bytesRead > 0 {
+			stallTimer.Reset(downloadStallTimeout)
+			bytesWritten, writeErr := file.Write(buf[:bytesRead])
+			written += int64(bytesWritten)
+			if time.Since(lastProgress) >= downloadProgressInterval {
+				progress(written, total)
+				lastProgress = time.Now()
+			}
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				if ctx.Err() == nil && attemptCtx.Err() != nil {
+					return fmt.Errorf("no data received for %s", downloadStallTimeout)
+				}
+				return readErr
+			}
+			break
+		}
+	}
+	// Always report where the download ended.
+	progress(written, total)
+	if total >= 0 && written != total {
+		return fmt.Errorf("download ended after %d of %d bytes", written, total)
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(partFilePath, filePath)
+	if err != nil {
+		return err
+	}
+	os.Remove(validatorFilePath)
+	return nil
+}
+
+// parseContentRange parses a "bytes start-end/size" Content-Range header. The
+// size is -1 if the server did not send it.
+func parseContentRange(contentRange string) (start int64, size int64, err error) {
+	rangeSpec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	byteRange, sizeString, ok := strings.Cut(rangeSpec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	startString, _, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	start, err = strconv.ParseInt(startString, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	size = -1
+	if sizeString != "*" {
+		size, err = strconv.ParseInt(sizeString, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+		}
+	}
+	return start, size, nil
+}
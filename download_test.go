@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testDriverArchive is the content served by the download tests.
+var testDriverArchive = bytes.Repeat([]byte("playwright"), 100_000)
+
+// downloadServer serves testDriverArchive with the given ETag and records
+// the requests it gets and the status codes it responds with.
+type downloadServer struct {
+	etag     string
+	mutex    sync.Mutex
+	requests []*http.Request
+	statuses []int
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (server *downloadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	recorder.Header().Set("ETag", server.etag)
+	http.ServeContent(recorder, r, "driver.zip", time.Time{}, bytes.NewReader(testDriverArchive))
+	server.mutex.Lock()
+	server.requests = append(server.requests, r)
+	server.statuses = append(server.statuses, recorder.status)
+	server.mutex.Unlock()
+}
+
+// checkDownloaded checks that filePath holds testDriverArchive and that the
+// .part and validator files are gone.
+func checkDownloaded(t *testing.T, filePath string) {
+	t.Helper()
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, testDriverArchive) {
+		t.Errorf("downloaded %d bytes that do not match the %d bytes served", len(b), len(testDriverArchive))
+	}
+	for _, leftover := range []string{filePath + ".part", filePath + ".part.validator"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s was left behind", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestResumableDownload(t *testing.T) {
+	half := int64(len(testDriverArchive) / 2)
+	type testCase struct {
+		description string
+		serverETag  string
+		part        []byte
+		validator   string
+		// Two URLs for the same server let a failed attempt be retried
+		// without waiting for the backoff.
+		urls       int
+		wantRange  string
+		wantStatus []int
+	}
+	tests := []testCase{{
+		description: "fresh download",
+		serverETag:  `"v1"`,
+		urls:        1,
+		wantStatus:  []int{http.StatusOK},
+	}, {
+		description: "resume",
+		serverETag:  `"v1"`,
+		part:        testDriverArchive[:half],
+		validator:   `"v1"`,
+		urls:        1,
+		wantRange:   "bytes=" + itoa(half) + "-",
+		wantStatus:  []int{http.StatusPartialContent},
+	}, {
+		description: "changed ETag restarts from zero",
+		serverETag:  `"v2"`,
+		part:        bytes.Repeat([]byte("x"), int(half)),
+		validator:   `"v1"`,
+		urls:        1,
+		wantRange:   "bytes=" + itoa(half) + "-",
+		wantStatus:  []int{http.StatusOK},
+	}, {
+		description: "stale part file is discarded",
+		serverETag:  `"v1"`,
+		part:        append(bytes.Clone(testDriverArchive), "stale"...),
+		validator:   `"v1"`,
+		urls:        2,
+		wantRange:   "bytes=" + itoa(int64(len(testDriverArchive))+5) + "-",
+		wantStatus:  []int{http.StatusRequestedRangeNotSatisfiable, http.StatusOK},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			server := &downloadServer{etag: tt.serverETag}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+			filePath := filepath.Join(t.TempDir(), "driver.zip")
+			if tt.part != nil {
+				err := os.WriteFile(filePath+".part", tt.part, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(filePath+".part.validator", []byte(tt.validator), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			var urls []string
+			for range tt.urls {
+				urls = append(urls, httpServer.URL)
+			}
+			err := resumableDownload(context.Background(), httpServer.Client(), urls, filePath, func(string) {}, func(int64, int64) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDownloaded(t, filePath)
+			server.mutex.Lock()
+			defer server.mutex.Unlock()
+			if len(server.statuses) != len(tt.wantStatus) {
+				t.Fatalf("got responses %v, want %v", server.statuses, tt.wantStatus)
+			}
+			for i, status := range server.statuses {
+				if status != tt.wantStatus[i] {
+					t.Errorf("response %d: got status %d, want %d", i, status, tt.wantStatus[i])
+				}
+			}
+			firstRequest := server.requests[0]
+			if got := firstRequest.Header.Get("Range"); got != tt.wantRange {
+				t.Errorf("got Range %q, want %q", got, tt.wantRange)
+			}
+			wantIfRange := ""
+			if tt.wantRange != "" {
+				wantIfRange = tt.validator
+			}
+			if got := firstRequest.Header.Get("If-Range"); got != wantIfRange {
+				t.Errorf("got If-Range %q, want %q", got, wantIfRange)
+			}
+		})
+	}
+}
+
+func TestResumableDownloadStall(t *testing.T) {
+	defer func(timeout time.Duration) { downloadStallTimeout = timeout }(downloadStallTimeout)
+	downloadStallTimeout = 100 * time.Millisecond
+	half := len(testDriverArchive) / 2
+	var mutex sync.Mutex
+	var ranges []string
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mutex.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		if !first {
+			http.ServeContent(w, r, "driver.zip", time.Time{}, bytes.NewReader(testDriverArchive))
+			return
+		}
+		// Send half of the file, then stop sending until the client
+		// gives up.
+		w.Header().Set("Content-Length", itoa(int64(len(testDriverArchive))))
+		w.Write(testDriverArchive[:half])
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+	}))
+	defer httpServer.Close()
+	filePath := filepath.Join(t.TempDir(), "driver.zip")
+	err := resumableDownload(context.Background(), httpServer.Client(), []string{httpServer.URL, httpServer.URL}, filePath, func(string) {}, func(int64, int64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, filePath)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(ranges) != 2 {
+		t.Fatalf("got %d requests, want 2", len(ranges))
+	}
+	if want := "bytes=" + itoa(int64(half)) + "-"; ranges[1] != want {
+		t.Errorf("retry: got Range %q, want %q", ranges[1], want)
+	}
+}
+
+func TestResumableDownloadProgress(t *testing.T) {
+	// With an interval longer than the test, only the first and the final
+	// progress are reported, however long each read takes.
+	defer func(interval time.Duration) { downloadProgressInterval = interval }(downloadProgressInterval)
+	downloadProgressInterval = time.Hour
+	httpServer := httptest.NewServer(&downloadServer{etag: `"v1"`})
+	defer httpServer.Close()
+	filePath := filepath.Join(t.TempDir(), "driver.zip")
+	type progress struct{ written, total int64 }
+	var got []progress
+	err := resumableDownload(context.Background(), httpServer.Client(), []string{httpServer.URL}, filePath, func(string) {}, func(written, total int64) {
+		got = append(got, progress{written, total})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(testDriverArchive))
+	want := []progress{{0, size}, {size, size}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got progress %v, want %v", got, want)
+	}
+}
+
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
@@ -2,21 +2,48 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// HealthCheck is the result of one check of the /driver health report.
+type HealthCheck struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Status is "ok", "warning" (things work but may not as expected) or
+	// "error" (something will not work until it is fixed).
+	Status  string `json:"status"`
+	Message string `json:"message"`
+
+	// Remediation tells the user how to fix a warning or error.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// minFreeDiskSpace is the free disk space below which the health report
+// warns. A browser build takes a few hundred megabytes and profiles grow
+// with the cache.
+const minFreeDiskSpace = 1 << 30
+
 func (backend *Backend) driver(w http.ResponseWriter, r *http.Request) {
 	type Response struct {
-		CurrentVersion  string `json:"currentVersion"`
-		RequiredVersion string `json:"requiredVersion"`
-		Error           string `json:"error"`
+		CurrentVersion  string        `json:"currentVersion"`
+		RequiredVersion string        `json:"requiredVersion"`
+		Error           string        `json:"error"`
+		Checks          []HealthCheck `json:"checks"`
 	}
 	if r.Method != "GET" {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
@@ -36,31 +63,251 @@ func (backend *Backend) driver(w http.ResponseWriter, r *http.Request) {
 			slog.Error(err.Error())
 		}
 	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
 	var response Response
 	response.RequiredVersion = backend.PlaywrightDriver.Version
-	fileInfo, err := os.Stat(filepath.Join(backend.PlaywrightRunOptions.DriverDirectory, "package", "cli.js"))
+	driverCheck := HealthCheck{ID: "driver", Name: "Playwright driver"}
+	response.CurrentVersion, response.Error = backend.checkDriver(ctx, &driverCheck)
+	response.Checks = []HealthCheck{
+		driverCheck,
+		backend.checkNode(ctx),
+		checkBrowsers(),
+		backend.checkBrowserLauncher(),
+		backend.checkCDP(ctx),
+		backend.checkProfilesDirectory(),
+		backend.checkDiskSpace(),
+	}
+	writeResponse(w, r, response)
+}
+
+// checkDriver runs `cli.js --version` and returns the version of the
+// installed driver, or the error that is reported as the response's Error
+// ("ErrNotExist" if the driver is not installed).
+func (backend *Backend) checkDriver(ctx context.Context, check *HealthCheck) (currentVersion string, errorMessage string) {
+	driverDirectory := backend.PlaywrightRunOptions.DriverDirectory
+	remediation := "Install the driver from the install driver window, or import a driver bundle."
+	fileInfo, err := os.Stat(filepath.Join(driverDirectory, "package", "cli.js"))
+	if err != nil || fileInfo.IsDir() {
+		check.Status = "error"
+		check.Remediation = remediation
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			check.Message = fmt.Sprintf("driver %s is not installed in %s", backend.PlaywrightDriver.Version, driverDirectory)
+			return "", "ErrNotExist"
+		}
+		check.Message = err.Error()
+		return "", err.Error()
+	}
+	cmd := backend.PlaywrightDriver.Command("--version")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	err = cmd.Start()
+	if err == nil {
+		stopKill := context.AfterFunc(ctx, func() {
+			cmd.Process.Kill()
+		})
+		err = cmd.Wait()
+		stopKill()
+	}
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			response.Error = "ErrNotExist"
-			writeResponse(w, r, response)
-			return
+		errorMessage = fmt.Sprintf("could not run driver: %v", err)
+		check.Status = "error"
+		check.Message = errorMessage
+		check.Remediation = "Reinstall the driver, the files in " + driverDirectory + " may be damaged."
+		return "", errorMessage
+	}
+	currentVersion = string(bytes.TrimSpace(output.Bytes()))
+	if !strings.Contains(currentVersion, backend.PlaywrightDriver.Version) {
+		check.Status = "error"
+		check.Message = fmt.Sprintf("%s is installed in %s, need %s", currentVersion, driverDirectory, backend.PlaywrightDriver.Version)
+		check.Remediation = remediation
+		return currentVersion, ""
+	}
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("%s in %s", currentVersion, driverDirectory)
+	return currentVersion, ""
+}
+
+// checkNode checks the Node.js binary the driver runs on, which comes with
+// the driver unless PLAYWRIGHT_NODEJS_PATH is set.
+func (backend *Backend) checkNode(ctx context.Context) HealthCheck {
+	check := HealthCheck{ID: "node", Name: "Node.js"}
+	nodePath := os.Getenv("PLAYWRIGHT_NODEJS_PATH")
+	remediation := "Reinstall the driver, it comes with its own Node.js."
+	if nodePath == "" {
+		node := "node"
+		if runtime.GOOS == "windows" {
+			node = "node.exe"
 		}
-		response.Error = err.Error()
-		writeResponse(w, r, response)
-		return
+		nodePath = filepath.Join(backend.PlaywrightRunOptions.DriverDirectory, node)
+	} else {
+		remediation = "Fix or unset PLAYWRIGHT_NODEJS_PATH."
 	}
-	if fileInfo.IsDir() {
-		response.Error = "ErrNotExist"
-		writeResponse(w, r, response)
-		return
+	if !isExecutableFile(nodePath) {
+		check.Status = "error"
+		check.Message = fmt.Sprintf("%s is missing or not executable", nodePath)
+		check.Remediation = remediation
+		return check
 	}
-	cmd := backend.PlaywrightDriver.Command("--version")
-	output, err := cmd.Output()
+	output, err := exec.CommandContext(ctx, nodePath, "--version").Output()
 	if err != nil {
-		response.Error = fmt.Sprintf("could not run driver: %v", err)
-		writeResponse(w, r, response)
-		return
+		check.Status = "error"
+		check.Message = fmt.Sprintf("could not run %s: %v", nodePath, err)
+		check.Remediation = remediation
+		return check
 	}
-	response.CurrentVersion = string(bytes.TrimSpace(output))
-	writeResponse(w, r, response)
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("%s (%s)", nodePath, bytes.TrimSpace(output))
+	return check
+}
+
+// checkBrowsers lists the browser builds installed by Playwright. They are
+// only needed for launching with Playwright, attaching over CDP uses the
+// browser installed on the machine.
+func checkBrowsers() HealthCheck {
+	check := HealthCheck{ID: "browsers", Name: "Playwright browsers"}
+	browsersDirectory, err := playwrightBrowsersDirectory()
+	if err != nil {
+		check.Status = "warning"
+		check.Message = err.Error()
+		return check
+	}
+	dirEntries, err := os.ReadDir(browsersDirectory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		check.Status = "warning"
+		check.Message = err.Error()
+		return check
+	}
+	var builds []string
+	for _, dirEntry := range dirEntries {
+		// Builds are named <browser>-<revision>, e.g. chromium-1200.
+		if dirEntry.IsDir() && strings.Contains(dirEntry.Name(), "-") && !strings.HasPrefix(dirEntry.Name(), ".") {
+			builds = append(builds, dirEntry.Name())
+		}
+	}
+	if len(builds) == 0 {
+		check.Status = "warning"
+		check.Message = fmt.Sprintf("no browsers installed in %s", browsersDirectory)
+		check.Remediation = "Install Chromium, Firefox or WebKit from the install driver window to launch browsers with Playwright."
+		return check
+	}
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("%s in %s", strings.Join(builds, ", "), browsersDirectory)
+	return check
+}
+
+// checkBrowserLauncher checks that the browser OpenBrowser attaches to is
+// installed.
+func (backend *Backend) checkBrowserLauncher() HealthCheck {
+	check := HealthCheck{ID: "browser", Name: "Browser"}
+	browserLauncher, err := backend.SelectedBrowserLauncher()
+	if err != nil {
+		check.Status = "error"
+		check.Message = err.Error()
+		check.Remediation = "Install Google Chrome, Microsoft Edge, Brave or Chromium, or select another browser."
+		return check
+	}
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("%s (%s)", browserLauncher.Name, browserLauncher.ExecutablePath)
+	return check
+}
+
+// checkCDP checks that the remote debugging port of the default profile is
+// either served by our browser or free for it to use.
+func (backend *Backend) checkCDP(ctx context.Context) HealthCheck {
+	check := HealthCheck{ID: "cdp", Name: "Remote debugging"}
+	profileDirectory, err := backend.profileDirectory(DefaultProfileName)
+	if err != nil {
+		check.Status = "error"
+		check.Message = err.Error()
+		return check
+	}
+	port, _, err := readDevToolsActivePort(profileDirectory)
+	if err == nil && port != 0 {
+		err = verifyCDP(ctx, port, profileDirectory)
+		if err == nil {
+			check.Status = "ok"
+			check.Message = fmt.Sprintf("browser is listening on port %d", port)
+			return check
+		}
+		var mismatchErr *CDPMismatchError
+		if errors.As(err, &mismatchErr) {
+			check.Status = "error"
+			check.Message = err.Error()
+			check.Remediation = "Close the other browser, or configure another port for the profile."
+			return check
+		}
+		// DevToolsActivePort is left behind when the browser exits.
+	}
+	config, err := readChromeProfileConfig(profileDirectory)
+	if err != nil {
+		check.Status = "warning"
+		check.Message = err.Error()
+		return check
+	}
+	if config.CDPPort == 0 {
+		config.CDPPort = backend.settings().DefaultCDPPort
+	}
+	if config.CDPPort == 0 {
+		check.Status = "ok"
+		check.Message = "browser is not running, a free port will be picked when it is launched"
+		return check
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(config.CDPPort))
+	if err != nil {
+		check.Status = "error"
+		check.Message = fmt.Sprintf("port %d configured for profile %q is in use by another program", config.CDPPort, DefaultProfileName)
+		check.Remediation = "Stop the program using the port, or configure another port for the profile."
+		return check
+	}
+	listener.Close()
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("browser is not running, port %d is free", config.CDPPort)
+	return check
+}
+
+// checkProfilesDirectory checks that browser profiles can be created.
+func (backend *Backend) checkProfilesDirectory() HealthCheck {
+	check := HealthCheck{ID: "profiles", Name: "Profiles folder"}
+	remediation := fmt.Sprintf("Make sure %s is writable by the current user.", backend.ProfilesDirectory)
+	err := os.MkdirAll(backend.ProfilesDirectory, 0755)
+	if err != nil {
+		check.Status = "error"
+		check.Message = err.Error()
+		check.Remediation = remediation
+		return check
+	}
+	file, err := os.CreateTemp(backend.ProfilesDirectory, ".healthcheck-*")
+	if err != nil {
+		check.Status = "error"
+		check.Message = fmt.Sprintf("%s is not writable: %v", backend.ProfilesDirectory, err)
+		check.Remediation = remediation
+		return check
+	}
+	file.Close()
+	os.Remove(file.Name())
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("%s is writable", backend.ProfilesDirectory)
+	return check
+}
+
+// checkDiskSpace checks the free space where profiles are stored.
+func (backend *Backend) checkDiskSpace() HealthCheck {
+	check := HealthCheck{ID: "diskSpace", Name: "Disk space"}
+	freeBytes, err := freeDiskSpace(filepath.Dir(backend.ProfilesDirectory))
+	if err != nil {
+		check.Status = "warning"
+		check.Message = fmt.Sprintf("could not determine free disk space: %v", err)
+		return check
+	}
+	message := fmt.Sprintf("%s free", HumanReadableFileSize(int64(freeBytes)))
+	if freeBytes < minFreeDiskSpace {
+		check.Status = "warning"
+		check.Message = message
+		check.Remediation = "Free up disk space, e.g. with the driver cleanup or by deleting unused profiles."
+		return check
+	}
+	check.Status = "ok"
+	check.Message = message
+	return check
 }
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// driverBundleManifestName is the name of the manifest at the root of a
+// driver bundle. A driver bundle is a zip containing the manifest, the driver
+// archive (as downloaded by installdriver) and optionally the Playwright
+// browser builds under "browsers/", for installing on machines without
+// internet access.
+const driverBundleManifestName = "bundle.json"
+
+// DriverBundleManifest describes the contents of a driver bundle. Repacked
+// is set if the driver archive was recreated from an installed driver rather
+// than copied as downloaded, in which case it can't match the checksum
+// manifest. The manifest comes from the bundle and is not trusted: a repacked
+// archive is only installed if the user allows unverified archives.
+type DriverBundleManifest struct {
+	DriverVersion string   `json:"driverVersion"`
+	Platform      string   `json:"platform"`
+	DriverArchive string   `json:"driverArchive"`
+	Repacked      bool     `json:"repacked"`
+	Browsers      []string `json:"browsers"`
+}
+
+// ImportDriver installs the driver from a local file, which is either a
+// driver archive (playwright-<version>-<platform>.zip) or a driver bundle
+// created with ExportDriverBundle. The driver version and platform must match
+// the ones we need. Browser builds in a bundle are installed into
+// PLAYWRIGHT_BROWSERS_PATH unless already present. The driver archive must
+// match the checksum manifest, if no checksum is known for it or it was
+// repacked it is only installed if allowUnverified is set.
+func (backend *Backend) ImportDriver(ctx context.Context, filePath string, allowUnverified bool) error {
+	return backend.importDriver(ctx, windowName(ctx), filePath, allowUnverified)
+}
+
+func (backend *Backend) importDriver(ctx context.Context, windowName string, filePath string, allowUnverified bool) error {
+	endDriverOperation, err := backend.beginDriverOperation("import")
+	if err != nil {
+		return err
+	}
+	defer endDriverOperation()
+	process := backend.beginProcess(ctx, windowName, fmt.Sprintf("Importing %s", filepath.Base(filePath)), 0)
+	err = backend.importDriverFile(process, filePath, allowUnverified)
+	if err != nil {
+		process.Fail(err)
+		return err
+	}
+	process.Finish("Imported Playwright driver")
+	return nil
+}
+
+func (backend *Backend) importDriverFile(process *Process, filePath string, allowUnverified bool) error {
+	ctx := process.Context()
+	version := backend.PlaywrightDriver.Version
+	platform := driverPlatform()
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("reading zip file %s: %w", filePath, err)
+	}
+	isBundle := false
+	for _, zipFile := range zipReader.File {
+		if zipFile.Name == driverBundleManifestName {
+			isBundle = true
+			break
+		}
+	}
+	zipReader.Close()
+	onFile := func(zipFile *zip.File, index int, count int) {
+		process.SetProgress(fmt.Sprintf("Unzipping %s", zipFile.Name), index, count)
+	}
+
+	if !isBundle {
+		// Archives keep the name they were downloaded with, check it if
+		// it hasn't been renamed. installDriverArchive checks the
+		// platform of what's inside either way.
+		baseName := filepath.Base(filePath)
+		if strings.HasPrefix(baseName, "playwright-") && baseName != driverArchiveName(version, platform) {
+			return fmt.Errorf("%s is not the driver archive for this machine, need %s", baseName, driverArchiveName(version, platform))
+		}
+		_, err = backend.verifyDriverArchive(version, platform, filePath, allowUnverified)
+		if err != nil {
+			return err
+		}
+		return backend.installDriverArchive(ctx, filePath, onFile)
+	}
+
+	bundleDirectory := filepath.Join(backend.DownloadsDirectory, "import-"+strconv.FormatInt(backend.Sequence.Add(1), 10))
+	err = os.MkdirAll(backend.DownloadsDirectory, 0755)
+	if err != nil {
+		return err
+	}
+	err = extractZip(ctx, filePath, bundleDirectory, onFile)
+	if err != nil {
+		return fmt.Errorf("unzipping %s: %w", filePath, err)
+	}
+	defer os.RemoveAll(bundleDirectory)
+	b, err := os.ReadFile(filepath.Join(bundleDirectory, driverBundleManifestName))
+	if err != nil {
+		return err
+	}
+	var manifest DriverBundleManifest
+	err = json.Unmarshal(b, &manifest)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", filePath, driverBundleManifestName, err)
+	}
+	if manifest.DriverVersion != version {
+		return fmt.Errorf("%s contains driver version %s, need %s", filePath, manifest.DriverVersion, version)
+	}
+	if manifest.Platform != platform {
+		return fmt.Errorf("%s is for platform %s, need %s", filePath, manifest.Platform, platform)
+	}
+	if !filepath.IsLocal(manifest.DriverArchive) {
+		return fmt.Errorf("%s: invalid driver archive %q", filePath, manifest.DriverArchive)
+	}
+	driverArchivePath := filepath.Join(bundleDirectory, manifest.DriverArchive)
+	_, err = backend.verifyDriverArchive(version, platform, driverArchivePath, allowUnverified)
+	if err != nil {
+		var mismatchErr *ChecksumMismatchError
+		if !errors.As(err, &mismatchErr) || !manifest.Repacked {
+			return err
+		}
+		if !allowUnverified {
+			return fmt.Errorf("%s: the driver archive was repacked from an installed driver and cannot be verified, allow installing it unverified to import it anyway", filePath)
+		}
+	}
+	err = backend.installDriverArchive(ctx, driverArchivePath, onFile)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Browsers) == 0 {
+		return nil
+	}
+	browsersDirectory := os.Getenv("PLAYWRIGHT_BROWSERS_PATH")
+	if browsersDirectory == "" {
+		return fmt.Errorf("PLAYWRIGHT_BROWSERS_PATH is not set, cannot install browsers")
+	}
+	err = os.MkdirAll(browsersDirectory, 0755)
+	if err != nil {
+		return err
+	}
+	for _, browser := range manifest.Browsers {
+		if !browserBuildPattern.MatchString(browser) {
+			return fmt.Errorf("%s: invalid browser %q", filePath, browser)
+		}
+		process.SetMessage(fmt.Sprintf("Installing %s", browser))
+		destDirectory := filepath.Join(browsersDirectory, browser)
+		if _, err := os.Stat(destDirectory); err == nil {
+			// Playwright never modifies an installed browser build, the
+			// one we have is the same.
+			continue
+		}
+		srcDirectory := filepath.Join(bundleDirectory, "browsers", browser)
+		err = validateBrowserBuild(srcDirectory)
+		if err != nil {
+			return fmt.Errorf("%s: invalid browser %q: %w", filePath, browser, err)
+		}
+		// The bundle is unpacked into the downloads directory, which may
+		// be on another volume than PLAYWRIGHT_BROWSERS_PATH.
+		err = moveDirectory(srcDirectory, destDirectory)
+		if err != nil {
+			return fmt.Errorf("installing %s: %w", browser, err)
+		}
+	}
+	return nil
+}
+
+// browserBuildPattern matches the names of Playwright's browser build
+// directories, e.g. "chromium-1200" or "chromium_headless_shell-1200".
+var browserBuildPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*-[0-9]+$`)
+
+// validateBrowserBuild checks a browser build directory from a bundle before
+// it is moved into PLAYWRIGHT_BROWSERS_PATH: it must be a directory, contain
+// only directories, regular files and symlinks, and every symlink must
+// resolve to something inside the directory, following chains of links.
+func validateBrowserBuild(directory string) error {
+	fileInfo, err := os.Lstat(directory)
+	if err != nil {
+		return err
+	}
+	if !fileInfo.IsDir() {
+		return fmt.Errorf("%s is not a directory", directory)
+	}
+	root, err := os.OpenRoot(directory)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return filepath.WalkDir(directory, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case dirEntry.IsDir(), dirEntry.Type().IsRegular():
+			return nil
+		case dirEntry.Type()&fs.ModeSymlink != 0:
+			relativePath, err := filepath.Rel(directory, filePath)
+			if err != nil {
+				return err
+			}
+			// os.Root refuses to follow links out of the directory.
+			_, err = root.Stat(relativePath)
+			if err != nil {
+				return fmt.Errorf("symlink %s: %w", relativePath, err)
+			}
+			return nil
+		default:
+			return fmt.Errorf("%s: unsupported file type %s", filePath, dirEntry.Type())
+		}
+	})
+}
+
+// verifyDownloadedDriverArchive reports whether the downloaded driver
+// archive is intact: it matches its checksum, or no checksum is known.
+func (backend *Backend) verifyDownloadedDriverArchive(version, platform, filePath string) bool {
+	_, err := backend.verifyDriverArchive(version, platform, filePath, true)
+	return err == nil
+}
+
+// ExportDriverBundle writes a driver bundle for this machine's platform to
+// destFilePath, for importing with ImportDriver on machines without internet
+// access. If includeBrowsers is set, the Playwright browser builds in
+// PLAYWRIGHT_BROWSERS_PATH are included as well.
+func (backend *Backend) ExportDriverBundle(ctx context.Context, destFilePath string, includeBrowsers bool) error {
+	return backend.exportDriverBundle(ctx, windowName(ctx), destFilePath, includeBrowsers)
+}
+
+func (backend *Backend) exportDriverBundle(ctx context.Context, windowName string, destFilePath string, includeBrowsers bool) error {
+	process := backend.beginProcess(ctx, windowName, fmt.Sprintf("Exporting %s", filepath.Base(destFilePath)), 0)
+	err := backend.writeDriverBundle(process, destFilePath, includeBrowsers)
+	if err != nil {
+		process.Fail(err)
+		return err
+	}
+	process.Finish(fmt.Sprintf("Exported %s", destFilePath))
+	return nil
+}
+
+func (backend *Backend) writeDriverBundle(process *Process, destFilePath string, includeBrowsers bool) (err error) {
+	ctx := process.Context()
+	version := backend.PlaywrightDriver.Version
+	platform := driverPlatform()
+	driverDirectory := backend.PlaywrightRunOptions.DriverDirectory
+	installedVersion, err := readDriverVersion(driverDirectory)
+	if err != nil {
+		return fmt.Errorf("no driver installed in %s: %w", driverDirectory, err)
+	}
+	if installedVersion != version {
+		return fmt.Errorf("installed driver version %s does not match required version %s", installedVersion, version)
+	}
+	manifest := DriverBundleManifest{
+		DriverVersion: version,
+		Platform:      platform,
+		DriverArchive: driverArchiveName(version, platform),
+		Browsers:      []string{},
+	}
+
+	// Write to a temporary file and rename it into place, so that a failed
+	// export doesn't leave a truncated bundle behind.
+	tempFilePath := destFilePath + ".part"
+	file, err := os.OpenFile(tempFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tempFilePath)
+		}
+	}()
+	zipWriter := zip.NewWriter(file)
+
+	// Prefer the archive exactly as it was downloaded, so that it still
+	// matches the checksum manifest. An archive whose checksum is unknown
+	// is copied as well, the importing side decides whether to trust it.
+	process.SetMessage("Adding driver")
+	driverArchiveWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: manifest.DriverArchive, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	downloadedArchivePath := filepath.Join(backend.DownloadsDirectory, manifest.DriverArchive)
+	if _, statErr := os.Stat(downloadedArchivePath); statErr == nil && backend.verifyDownloadedDriverArchive(version, platform, downloadedArchivePath) {
+		archiveFile, err := os.Open(downloadedArchivePath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(driverArchiveWriter, archiveFile)
+		archiveFile.Close()
+		if err != nil {
+			return err
+		}
+	} else {
+		manifest.Repacked = true
+		driverZipWriter := zip.NewWriter(driverArchiveWriter)
+		err = addDirectoryToZip(ctx, driverZipWriter, driverDirectory, "")
+		if err != nil {
+			return err
+		}
+		err = driverZipWriter.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if includeBrowsers {
+		browsersDirectory := os.Getenv("PLAYWRIGHT_BROWSERS_PATH")
+		dirEntries, err := os.ReadDir(browsersDirectory)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for _, dirEntry := range dirEntries {
+			// Skip Playwright's lock and marker files and anything that
+			// isn't a browser build.
+			if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+				continue
+			}
+			process.SetMessage(fmt.Sprintf("Adding %s", dirEntry.Name()))
+			err = addDirectoryToZip(ctx, zipWriter, filepath.Join(browsersDirectory, dirEntry.Name()), path.Join("browsers", dirEntry.Name()))
+			if err != nil {
+				return err
+			}
+			manifest.Browsers = append(manifest.Browsers, dirEntry.Name())
+		}
+	}
+
+	manifestWriter, err := zipWriter.Create(driverBundleManifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifestWriter)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(&manifest)
+	if err != nil {
+		return err
+	}
+	err = zipWriter.Close()
+	if err != nil {
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempFilePath, destFilePath)
+}
+
+// addDirectoryToZip adds the contents of directory to zipWriter under prefix,
+// preserving permissions and symlinks.
+func addDirectoryToZip(ctx context.Context, zipWriter *zip.Writer, directory string, prefix string) error {
+	return filepath.WalkDir(directory, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		relativePath, err := filepath.Rel(directory, filePath)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return nil
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(fileInfo)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(relativePath))
+		if dirEntry.IsDir() {
+			header.Name += "/"
+			_, err = zipWriter.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if dirEntry.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(writer, filepath.ToSlash(target))
+			return err
+		}
+		if !dirEntry.Type().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(writer, file)
+		return err
+	})
+}
+
+// importdriver serves POST /importdriver/ (form fields: filePath,
+// allowUnverified, windowName).
+func (backend *Backend) importdriver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filePath := r.Form.Get("filePath")
+	if filePath == "" {
+		http.Error(w, "MissingFilePath", http.StatusBadRequest)
+		return
+	}
+	allowUnverified, _ := strconv.ParseBool(r.Form.Get("allowUnverified"))
+	err := backend.importDriver(r.Context(), r.Form.Get("windowName"), filePath, allowUnverified)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "success: imported "+filePath)
+}
+
+// exportdriver serves POST /exportdriver/ (form fields: filePath,
+// includeBrowsers, windowName).
+func (backend *Backend) exportdriver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filePath := r.Form.Get("filePath")
+	if filePath == "" {
+		http.Error(w, "MissingFilePath", http.StatusBadRequest)
+		return
+	}
+	includeBrowsers, _ := strconv.ParseBool(r.Form.Get("includeBrowsers"))
+	err := backend.exportDriverBundle(r.Context(), r.Form.Get("windowName"), filePath, includeBrowsers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "success: exported "+filePath)
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"changeme/stacktrace"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Each driver version is unpacked into its own subdirectory of
+// Backend.DriversDirectory, named after the version, so that several versions
+// can be installed side by side. PlaywrightRunOptions.DriverDirectory is the
+// subdirectory of the version we need.
+//
+// driverPinsFileName lists the pinned versions, which are never removed by
+// CleanupDrivers.
+const driverPinsFileName = "pinned.json"
+
+// DriverVersion is an installed driver version.
+type DriverVersion struct {
+	Version   string `json:"version"`
+	Directory string `json:"directory"`
+	Size      int64  `json:"size"`
+	SizeText  string `json:"sizeText"`
+	Pinned    bool   `json:"pinned"`
+	Required  bool   `json:"required"`
+}
+
+// DriverArchive is a downloaded driver archive (or a leftover partial
+// download) in Backend.DownloadsDirectory.
+type DriverArchive struct {
+	Name     string `json:"name"`
+	FilePath string `json:"filePath"`
+	Size     int64  `json:"size"`
+	SizeText string `json:"sizeText"`
+	Stale    bool   `json:"stale"`
+}
+
+// DriverInventory lists the installed driver versions and downloaded
+// archives.
+type DriverInventory struct {
+	RequiredVersion string          `json:"requiredVersion"`
+	Drivers         []DriverVersion `json:"drivers"`
+	Archives        []DriverArchive `json:"archives"`
+	TotalSize       int64           `json:"totalSize"`
+	TotalSizeText   string          `json:"totalSizeText"`
+}
+
+// DriverCleanup is the result of CleanupDrivers.
+type DriverCleanup struct {
+	Removed   []string `json:"removed"`
+	Freed     int64    `json:"freed"`
+	FreedText string   `json:"freedText"`
+}
+
+// legacyDriverEntries are the entries of a driver archive, which are moved
+// by migrateDriverDirectory. Anything else in the drivers directory is left
+// alone, it may not be ours.
+var legacyDriverEntries = []string{"node", "node.exe", "package"}
+
+// migrateDriverDirectory moves a driver unpacked directly into
+// driversDirectory (the layout before drivers were versioned) into its
+// version subdirectory, and any driver archives next to it into
+// downloadsDirectory. Only the entries of a driver archive are moved, and
+// only if they make up a driver.
+func migrateDriverDirectory(driversDirectory string, downloadsDirectory string) error {
+	version, err := readDriverVersion(driversDirectory)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if _, ok := parseDriverVersion(version); !ok {
+		return fmt.Errorf("%s contains a driver with invalid version %q", driversDirectory, version)
+	}
+	dirEntries, err := os.ReadDir(driversDirectory)
+	if err != nil {
+		return err
+	}
+	versionDirectory := filepath.Join(driversDirectory, version)
+	err = os.MkdirAll(versionDirectory, 0755)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(downloadsDirectory, 0755)
+	if err != nil {
+		return err
+	}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		var destFilePath string
+		switch {
+		case slices.Contains(legacyDriverEntries, name):
+			destFilePath = filepath.Join(versionDirectory, name)
+		case !dirEntry.IsDir() && strings.HasPrefix(name, "playwright-") && strings.HasSuffix(name, ".zip"):
+			destFilePath = filepath.Join(downloadsDirectory, name)
+		default:
+			continue
+		}
+		err = os.Rename(filepath.Join(driversDirectory, name), destFilePath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDriverVersion splits a driver version such as "1.57.0" or
+// "1.58.0-alpha-2025-11-20" into its numeric parts. It reports false if the
+// version is not of that form.
+func parseDriverVersion(version string) ([]int, bool) {
+	release, _, _ := strings.Cut(version, "-")
+	fields := strings.Split(release, ".")
+	if len(fields) < 2 || len(fields) > 4 {
+		return nil, false
+	}
+	numbers := make([]int, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || strconv.Itoa(n) != field {
+			return nil, false
+		}
+		numbers[i] = n
+	}
+	if strings.ContainsAny(version, `/\`) || !filepath.IsLocal(version) {
+		return nil, false
+	}
+	return numbers, true
+}
+
+// compareDriverVersions orders driver versions numerically, so that 1.9.0
+// comes before 1.10.0. A prerelease comes before its release. Versions that
+// can't be parsed come first, in string order.
+func compareDriverVersions(a, b string) int {
+	aNumbers, aOK := parseDriverVersion(a)
+	bNumbers, bOK := parseDriverVersion(b)
+	switch {
+	case !aOK && !bOK:
+		return cmp.Compare(a, b)
+	case !aOK:
+		return -1
+	case !bOK:
+		return 1
+	}
+	if c := slices.Compare(aNumbers, bNumbers); c != 0 {
+		return c
+	}
+	_, aPrerelease, aHasPrerelease := strings.Cut(a, "-")
+	_, bPrerelease, bHasPrerelease := strings.Cut(b, "-")
+	switch {
+	case aHasPrerelease && !bHasPrerelease:
+		return -1
+	case !aHasPrerelease && bHasPrerelease:
+		return 1
+	}
+	return cmp.Compare(aPrerelease, bPrerelease)
+}
+
+// directorySize returns the total size of the files in a directory.
+func directorySize(directory string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(directory, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !dirEntry.Type().IsRegular() {
+			return nil
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		size += fileInfo.Size()
+		return nil
+	})
+	return size, err
+}
+
+// driverPins returns the pinned driver versions.
+func (backend *Backend) driverPins() ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(backend.DriversDirectory, driverPinsFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, stacktrace.New(err)
+	}
+	var pins []string
+	err = json.Unmarshal(b, &pins)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", driverPinsFileName, err)
+	}
+	return pins, nil
+}
+
+// ListDrivers returns the installed driver versions and downloaded archives
+// with their disk usage.
+func (backend *Backend) ListDrivers() (DriverInventory, error) {
+	inventory := DriverInventory{
+		RequiredVersion: backend.PlaywrightDriver.Version,
+		Drivers:         []DriverVersion{},
+		Archives:        []DriverArchive{},
+	}
+	pins, err := backend.driverPins()
+	if err != nil {
+		return inventory, err
+	}
+	dirEntries, err := os.ReadDir(backend.DriversDirectory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return inventory, stacktrace.New(err)
+	}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		directory := filepath.Join(backend.DriversDirectory, dirEntry.Name())
+		version, err := readDriverVersion(directory)
+		if err != nil || version != dirEntry.Name() {
+			// Staging, previous and rollback directories are accounted
+			// for by CleanupDrivers but aren't versions of their own.
+			continue
+		}
+		size, err := directorySize(directory)
+		if err != nil {
+			return inventory, stacktrace.New(err)
+		}
+		inventory.Drivers = append(inventory.Drivers, DriverVersion{
+			Version:   version,
+			Directory: directory,
+			Size:      size,
+			SizeText:  HumanReadableFileSize(size),
+			Pinned:    slices.Contains(pins, version),
+			Required:  version == backend.PlaywrightDriver.Version,
+		})
+		inventory.TotalSize += size
+	}
+	slices.SortFunc(inventory.Drivers, func(a, b DriverVersion) int {
+		return compareDriverVersions(a.Version, b.Version)
+	})
+	dirEntries, err = os.ReadDir(backend.DownloadsDirectory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return inventory, stacktrace.New(err)
+	}
+	requiredArchiveName := driverArchiveName(backend.PlaywrightDriver.Version, driverPlatform())
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			return inventory, stacktrace.New(err)
+		}
+		stale := !strings.HasPrefix(dirEntry.Name(), requiredArchiveName)
+		for _, pin := range pins {
+			if strings.HasPrefix(dirEntry.Name(), driverArchiveName(pin, driverPlatform())) {
+				stale = false
+			}
+		}
+		inventory.Archives = append(inventory.Archives, DriverArchive{
+			Name:     dirEntry.Name(),
+			FilePath: filepath.Join(backend.DownloadsDirectory, dirEntry.Name()),
+			Size:     fileInfo.Size(),
+			SizeText: HumanReadableFileSize(fileInfo.Size()),
+			Stale:    stale,
+		})
+		inventory.TotalSize += fileInfo.Size()
+	}
+	inventory.TotalSizeText = HumanReadableFileSize(inventory.TotalSize)
+	return inventory, nil
+}
+
+// PinDriver pins or unpins an installed driver version. Pinned versions (and
+// their archives) are kept by CleanupDrivers.
+func (backend *Backend) PinDriver(version string, pinned bool) error {
+	if _, ok := parseDriverVersion(version); !ok {
+		return fmt.Errorf("invalid driver version %q", version)
+	}
+	if pinned {
+		if _, err := readDriverVersion(filepath.Join(backend.DriversDirectory, version)); err != nil {
+			return fmt.Errorf("driver version %s is not installed", version)
+		}
+	}
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	pins, err := backend.driverPins()
+	if err != nil {
+		return err
+	}
+	pins = slices.DeleteFunc(pins, func(pin string) bool { return pin == version })
+	if pinned {
+		pins = append(pins, version)
+	}
+	slices.SortFunc(pins, compareDriverVersions)
+	b, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	err = os.WriteFile(filepath.Join(backend.DriversDirectory, driverPinsFileName), b, 0644)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// CleanupDrivers removes the driver versions that are neither required nor
+// pinned (including their staging and previous directories), downloaded
+// archives and partial downloads for those versions, and leftovers from
+// interrupted imports. The previous version of the required driver is kept
+// for RollbackDriver. Only directories that hold a driver of the version they
+// are named after, or are the staging, previous or rollback directory of a
+// version, are removed. Anything else in the drivers directory is left alone.
+// It fails while a driver install or import is in progress.
+func (backend *Backend) CleanupDrivers(ctx context.Context) (DriverCleanup, error) {
+	cleanup := DriverCleanup{Removed: []string{}}
+	endDriverOperation, err := backend.beginDriverOperation("cleanup")
+	if err != nil {
+		return cleanup, err
+	}
+	defer endDriverOperation()
+	pins, err := backend.driverPins()
+	if err != nil {
+		return cleanup, err
+	}
+	keep := append([]string{backend.PlaywrightDriver.Version}, pins...)
+	remove := func(filePath string) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fileInfo, err := os.Lstat(filePath)
+		if err != nil {
+			return err
+		}
+		size := fileInfo.Size()
+		if fileInfo.IsDir() {
+			size, err = directorySize(filePath)
+			if err != nil {
+				return err
+			}
+		}
+		err = os.RemoveAll(filePath)
+		if err != nil {
+			return err
+		}
+		slog.Info("removed " + filePath)
+		cleanup.Removed = append(cleanup.Removed, filePath)
+		cleanup.Freed += size
+		return nil
+	}
+	dirEntries, err := os.ReadDir(backend.DriversDirectory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cleanup, stacktrace.New(err)
+	}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		name := dirEntry.Name()
+		version := name
+		for _, suffix := range []string{".staging", ".previous", ".rollback"} {
+			version = strings.TrimSuffix(version, suffix)
+		}
+		if _, ok := parseDriverVersion(version); !ok || slices.Contains(keep, version) {
+			continue
+		}
+		if version == name {
+			installedVersion, err := readDriverVersion(filepath.Join(backend.DriversDirectory, name))
+			if err != nil || installedVersion != version {
+				continue
+			}
+		}
+		err = remove(filepath.Join(backend.DriversDirectory, name))
+		if err != nil {
+			return cleanup, err
+		}
+	}
+	dirEntries, err = os.ReadDir(backend.DownloadsDirectory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cleanup, stacktrace.New(err)
+	}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() {
+			// Leftover from an interrupted ImportDriver.
+			if strings.HasPrefix(name, "import-") {
+				err = remove(filepath.Join(backend.DownloadsDirectory, name))
+				if err != nil {
+					return cleanup, err
+				}
+			}
+			continue
+		}
+		if !strings.HasPrefix(name, "playwright-") {
+			continue
+		}
+		stale := true
+		for _, version := range keep {
+			if strings.HasPrefix(name, driverArchiveName(version, driverPlatform())) {
+				stale = false
+			}
+		}
+		if !stale {
+			continue
+		}
+		err = remove(filepath.Join(backend.DownloadsDirectory, name))
+		if err != nil {
+			return cleanup, err
+		}
+	}
+	cleanup.FreedText = HumanReadableFileSize(cleanup.Freed)
+	return cleanup, nil
+}
+
+// drivers serves the driver inventory endpoints:
+//
+//	GET  /drivers/          returns the DriverInventory
+//	POST /drivers/pin/      pins or unpins a version (form fields: version, pinned)
+//	POST /drivers/cleanup/  runs CleanupDrivers and returns the DriverCleanup
+func (backend *Backend) drivers(w http.ResponseWriter, r *http.Request, pathTail string) {
+	writeResponse := func(w http.ResponseWriter, r *http.Request, response any) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(response)
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+	switch pathTail {
+	case "":
+		if r.Method != "GET" && r.Method != "HEAD" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inventory, err := backend.ListDrivers()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, r, inventory)
+	case "pin":
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		pinned, err := strconv.ParseBool(r.Form.Get("pinned"))
+		if err != nil {
+			http.Error(w, "InvalidPinned", http.StatusBadRequest)
+			return
+		}
+		err = backend.PinDriver(r.Form.Get("version"), pinned)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "cleanup":
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		cleanup, err := backend.CleanupDrivers(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, r, cleanup)
+	default:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}
+}
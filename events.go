@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// eventTopics are the names of the events the backend emits. They are the
+// topics that clients of /events can filter on.
+var eventTopics = []string{
+	"BrowserStatus",
+	"InstallDriverEvent",
+	"ProcessUpdate",
+	"SettingsChanged",
+	"StartupStatus",
+	"TabClosed",
+	"TabDetailUpdated",
+	"TabNavigated",
+	"TabOpened",
+	"WindowClosed",
+	"time",
+}
+
+// StreamEvent is an event as written to the /events stream. Sender is the
+// window the event was sent to, or empty if it was broadcast.
+type StreamEvent struct {
+	EventID   int64           `json:"eventID"`
+	Topic     string          `json:"topic"`
+	Sender    string          `json:"sender,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// eventSubscriber is a client of /events.
+type eventSubscriber struct {
+	topics []string // empty for all topics
+	events chan StreamEvent
+}
+
+// EventHub copies the events emitted to the webviews to the clients of
+// /events. Its zero value is ready to use.
+type EventHub struct {
+	mutex       sync.Mutex
+	sequence    int64
+	subscribers map[*eventSubscriber]struct{}
+}
+
+// subscribe adds a subscriber for the given topics, or all topics if there
+// are none.
+func (hub *EventHub) subscribe(topics []string) *eventSubscriber {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if hub.subscribers == nil {
+		hub.subscribers = make(map[*eventSubscriber]struct{})
+	}
+	subscriber := &eventSubscriber{
+		topics: topics,
+		events: make(chan StreamEvent, 256),
+	}
+	hub.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+// unsubscribe removes a subscriber, closing its channel if that has not
+// happened yet.
+func (hub *EventHub) unsubscribe(subscriber *eventSubscriber) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if _, ok := hub.subscribers[subscriber]; ok {
+		delete(hub.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+// publish sends an event to the subscribers of its topic. A subscriber
+// whose buffer is full has fallen too far behind, it is dropped rather than
+// holding up the backend and has to reconnect.
+func (hub *EventHub) publish(topic string, sender string, data any) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	hub.sequence++
+	if len(hub.subscribers) == 0 {
+		return
+	}
+	// Marshal the data now, it may be changed once the emitter returns.
+	b, err := json.Marshal(data)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	event := StreamEvent{
+		EventID:   hub.sequence,
+		Topic:     topic,
+		Sender:    sender,
+		Data:      b,
+		Timestamp: time.Now().Unix(),
+	}
+	for subscriber := range hub.subscribers {
+		if len(subscriber.topics) != 0 && !slices.Contains(subscriber.topics, topic) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			delete(hub.subscribers, subscriber)
+			close(subscriber.events)
+		}
+	}
+}
+
+// emitEvent emits an event to the webviews and to the clients of /events.
+// Events must be emitted through here rather than App.Event, otherwise they
+// do not reach /events.
+func (backend *Backend) emitEvent(event *application.CustomEvent) {
+	backend.Events.publish(event.Name, event.Sender, event.Data)
+	backend.App.Event.EmitEvent(event)
+}
+
+// emitWindowEvent is like emitEvent, but only the webview of the window named
+// by event.Sender receives the event, not the other windows. An event without
+// a sender is emitted to every window.
+func (backend *Backend) emitWindowEvent(event *application.CustomEvent) {
+	if event.Sender == "" {
+		backend.emitEvent(event)
+		return
+	}
+	backend.Events.publish(event.Name, event.Sender, event.Data)
+	if window, ok := backend.App.Window.GetByName(event.Sender); ok {
+		window.DispatchWailsEvent(event)
+	}
+}
+
+// events streams the backend's events as they are emitted, e.g. to observe
+// automation progress from a dashboard or the command line. Events are
+// written as Server-Sent Events if the client accepts text/event-stream and
+// as newline-delimited JSON otherwise.
+//
+// By default every topic (see eventTopics) is streamed, pass one or more
+// topic form fields to only receive those topics:
+//
+//	GET /events/?topic=ProcessUpdate&topic=TabOpened
+func (backend *Backend) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	topics := r.Form["topic"]
+	for _, topic := range topics {
+		if !slices.Contains(eventTopics, topic) {
+			http.Error(w, "InvalidTopic", http.StatusBadRequest)
+			return
+		}
+	}
+	subscriber := backend.Events.subscribe(topics)
+	defer backend.Events.unsubscribe(subscriber)
+	eventStream := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if eventStream {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	responseController := http.NewResponseController(w)
+	responseController.Flush()
+	// Proxies drop connections that are idle for too long, SSE comments
+	// keep the stream alive when the selected topics are quiet.
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-subscriber.events:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if eventStream {
+				_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.EventID, event.Topic, b)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", b)
+			}
+			if err != nil {
+				return
+			}
+			responseController.Flush()
+		case <-keepAlive.C:
+			if !eventStream {
+				continue
+			}
+			_, err := fmt.Fprint(w, ": keepalive\n\n")
+			if err != nil {
+				return
+			}
+			responseController.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// extractZip extracts a zip archive into destDirectory, which must not exist
+// yet. Entries that would end up outside destDirectory (absolute paths, ".."
+// components, or symlinks pointing out of the archive) are rejected. Files
+// are written through an os.Root so that a chain of symlinks created by
+// earlier entries cannot lead out of destDirectory either. File permissions
+// are preserved. onFile is called before each entry is extracted.
+//
+// If extraction fails or ctx is cancelled, destDirectory is removed.
+func extractZip(ctx context.Context, zipFilePath string, destDirectory string, onFile func(zipFile *zip.File, index int, count int)) (err error) {
+	zipReader, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return fmt.Errorf("reading zip file %s: %w", zipFilePath, err)
+	}
+	defer zipReader.Close()
+	err = os.Mkdir(destDirectory, 0755)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(destDirectory)
+		}
+	}()
+	root, err := os.OpenRoot(destDirectory)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	for i, zipFile := range zipReader.File {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		onFile(zipFile, i, len(zipReader.File))
+		err = extractZipFile(zipFile, root)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile extracts a single zip entry into root.
+func extractZipFile(zipFile *zip.File, root *os.Root) error {
+	name := strings.TrimSuffix(zipFile.Name, "/")
+	if !filepath.IsLocal(name) || strings.Contains(name, `\`) {
+		return fmt.Errorf("unzipping %s: path escapes the destination directory", zipFile.Name)
+	}
+	destFilePath := filepath.FromSlash(name)
+	mode := zipFile.Mode()
+	if mode.IsDir() {
+		err := root.MkdirAll(destFilePath, 0755)
+		if err != nil {
+			return fmt.Errorf("creating folder %s: %w", destFilePath, err)
+		}
+		return nil
+	}
+	err := root.MkdirAll(filepath.Dir(destFilePath), 0755)
+	if err != nil {
+		return fmt.Errorf("creating folder %s: %w", filepath.Dir(destFilePath), err)
+	}
+	srcFile, err := zipFile.Open()
+	if err != nil {
+		return fmt.Errorf("opening file for reading %s: %w", zipFile.Name, err)
+	}
+	defer srcFile.Close()
+	if mode&fs.ModeSymlink != 0 {
+		b, err := io.ReadAll(io.LimitReader(srcFile, 4096))
+		if err != nil {
+			return fmt.Errorf("unzipping %s: %w", zipFile.Name, err)
+		}
+		target := string(b)
+		// The target is relative to the directory containing the link and
+		// must stay inside the archive. This only looks at the text of the
+		// target, root rejects links that escape by way of other links.
+		if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), target)) {
+			return fmt.Errorf("unzipping %s: symlink target %q escapes the destination directory", zipFile.Name, target)
+		}
+		err = root.Symlink(filepath.FromSlash(target), destFilePath)
+		if err != nil {
+			return fmt.Errorf("creating symlink %s: %w", destFilePath, err)
+		}
+		return nil
+	}
+	if !mode.IsRegular() {
+		return fmt.Errorf("unzipping %s: unsupported file type %s", zipFile.Name, mode.Type())
+	}
+	perm := mode.Perm()
+	if perm == 0 || runtime.GOOS == "windows" {
+		// Archives created on Windows don't record permissions.
+		perm = 0644
+	}
+	// O_EXCL: a regular file must not replace something created by an
+	// earlier entry, in particular a symlink.
+	destFile, err := root.OpenFile(destFilePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm|0200)
+	if err != nil {
+		return fmt.Errorf("opening file for writing %s: %w", destFilePath, err)
+	}
+	_, err = io.Copy(destFile, srcFile)
+	if err != nil {
+		destFile.Close()
+		return fmt.Errorf("unzipping %s: %w", zipFile.Name, err)
+	}
+	err = destFile.Close()
+	if err != nil {
+		return fmt.Errorf("closing %s: %w", destFilePath, err)
+	}
+	return nil
+}
+
+// moveDirectory moves srcDirectory to destDirectory, which must not exist.
+// If they are on different volumes, where renaming fails, srcDirectory is
+// copied next to destDirectory and renamed into place from there, so that
+// destDirectory never holds a partial copy.
+func moveDirectory(srcDirectory string, destDirectory string) error {
+	err := os.Rename(srcDirectory, destDirectory)
+	if err == nil {
+		return nil
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		return err
+	}
+	// A dot name keeps a leftover copy out of listings that skip hidden
+	// entries, like Playwright's browsers directory.
+	tempDirectory := filepath.Join(filepath.Dir(destDirectory), "."+filepath.Base(destDirectory)+".part")
+	err = os.RemoveAll(tempDirectory)
+	if err != nil {
+		return err
+	}
+	err = copyDirectory(srcDirectory, tempDirectory)
+	if err != nil {
+		os.RemoveAll(tempDirectory)
+		return fmt.Errorf("copying %s: %w", srcDirectory, err)
+	}
+	err = os.Rename(tempDirectory, destDirectory)
+	if err != nil {
+		os.RemoveAll(tempDirectory)
+		return err
+	}
+	return os.RemoveAll(srcDirectory)
+}
+
+// copyDirectory copies srcDirectory to destDirectory, which must not exist,
+// preserving permissions and symlinks.
+func copyDirectory(srcDirectory string, destDirectory string) error {
+	return filepath.WalkDir(srcDirectory, func(srcPath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(srcDirectory, srcPath)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destDirectory, relativePath)
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case dirEntry.IsDir():
+			return os.Mkdir(destPath, fileInfo.Mode().Perm()|0700)
+		case dirEntry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, destPath)
+		case dirEntry.Type().IsRegular():
+			srcFile, err := os.Open(srcPath)
+			if err != nil {
+				return err
+			}
+			defer srcFile.Close()
+			destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fileInfo.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(destFile, srcFile)
+			if err != nil {
+				destFile.Close()
+				return err
+			}
+			return destFile.Close()
+		default:
+			return fmt.Errorf("%s: unsupported file type %s", srcPath, dirEntry.Type())
+		}
+	})
+}
+
+// swapDirectory atomically replaces directory with stagingDirectory. The
+// replaced directory is kept as directory + ".previous" (replacing any older
+// one) so that it can be restored with rollbackDirectory.
+func swapDirectory(stagingDirectory string, directory string) error {
+	previousDirectory := directory + ".previous"
+	err := os.RemoveAll(previousDirectory)
+	if err != nil {
+		return err
+	}
+	hadDirectory := true
+	err = os.Rename(directory, previousDirectory)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("moving %s aside (is the driver still running?): %w", directory, err)
+		}
+		hadDirectory = false
+	}
+	err = os.Rename(stagingDirectory, directory)
+	if err != nil {
+		if hadDirectory {
+			os.Rename(previousDirectory, directory)
+		}
+		return fmt.Errorf("moving %s into place: %w", stagingDirectory, err)
+	}
+	return nil
+}
+
+// rollbackDirectory swaps directory with the directory + ".previous" kept by
+// swapDirectory, so that a second rollback undoes the first.
+func rollbackDirectory(directory string) error {
+	previousDirectory := directory + ".previous"
+	if _, err := os.Stat(previousDirectory); err != nil {
+		return fmt.Errorf("no previous version of %s to roll back to", directory)
+	}
+	rollbackDirectory := directory + ".rollback"
+	err := os.RemoveAll(rollbackDirectory)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(previousDirectory, rollbackDirectory)
+	if err != nil {
+		return err
+	}
+	err = swapDirectory(rollbackDirectory, directory)
+	if err != nil {
+		os.Rename(rollbackDirectory, previousDirectory)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+type testZipEntry struct {
+	Name string
+	Mode fs.FileMode
+	Body string // the target for symlinks
+}
+
+// writeTestZip writes a zip file with the given entries and returns its
+// path.
+func writeTestZip(t *testing.T, entries []testZipEntry) string {
+	t.Helper()
+	zipFilePath := filepath.Join(t.TempDir(), "test.zip")
+	file, err := os.Create(zipFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zipWriter := zip.NewWriter(file)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.Name, Method: zip.Deflate}
+		header.SetMode(entry.Mode)
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.WriteString(writer, entry.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = zipWriter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zipFilePath
+}
+
+func TestExtractZip(t *testing.T) {
+	type testCase struct {
+		description string
+		entries     []testZipEntry
+		symlinks    bool
+		wantErr     bool
+		check       func(t *testing.T, destDirectory string)
+	}
+	tests := []testCase{{
+		description: "parent directory",
+		entries:     []testZipEntry{{Name: "../x", Mode: 0644, Body: "x"}},
+		wantErr:     true,
+	}, {
+		description: "nested parent directory",
+		entries:     []testZipEntry{{Name: "package/../../x", Mode: 0644, Body: "x"}},
+		wantErr:     true,
+	}, {
+		description: "absolute path",
+		entries:     []testZipEntry{{Name: "/tmp/x", Mode: 0644, Body: "x"}},
+		wantErr:     true,
+	}, {
+		description: "backslash",
+		entries:     []testZipEntry{{Name: `package\..\..\x`, Mode: 0644, Body: "x"}},
+		wantErr:     true,
+	}, {
+		description: "symlink out of the directory",
+		entries:     []testZipEntry{{Name: "link", Mode: fs.ModeSymlink | 0777, Body: "../.."}},
+		symlinks:    true,
+		wantErr:     true,
+	}, {
+		description: "absolute symlink",
+		entries:     []testZipEntry{{Name: "link", Mode: fs.ModeSymlink | 0777, Body: "/etc"}},
+		symlinks:    true,
+		wantErr:     true,
+	}, {
+		description: "file written through a chain of symlinks",
+		entries: []testZipEntry{
+			{Name: "package/", Mode: fs.ModeDir | 0755},
+			// package/up points at the destination directory and link
+			// at package/up/.., which looks local but is its parent.
+			{Name: "package/up", Mode: fs.ModeSymlink | 0777, Body: ".."},
+			{Name: "link", Mode: fs.ModeSymlink | 0777, Body: "package/up/.."},
+			{Name: "link/escaped", Mode: 0644, Body: "x"},
+		},
+		symlinks: true,
+		wantErr:  true,
+	}, {
+		description: "file written through a symlink inside the directory",
+		entries: []testZipEntry{
+			{Name: "package/", Mode: fs.ModeDir | 0755},
+			{Name: "link", Mode: fs.ModeSymlink | 0777, Body: "package"},
+			{Name: "link/cli.js", Mode: 0644, Body: "cli"},
+		},
+		symlinks: true,
+		check: func(t *testing.T, destDirectory string) {
+			b, err := os.ReadFile(filepath.Join(destDirectory, "package", "cli.js"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "cli" {
+				t.Errorf("got %q, want %q", b, "cli")
+			}
+		},
+	}, {
+		description: "file replacing a symlink",
+		entries: []testZipEntry{
+			{Name: "link", Mode: fs.ModeSymlink | 0777, Body: "target"},
+			{Name: "link", Mode: 0644, Body: "x"},
+		},
+		symlinks: true,
+		wantErr:  true,
+	}, {
+		description: "executable",
+		entries: []testZipEntry{
+			{Name: "node", Mode: 0755, Body: "node"},
+			{Name: "package/cli.js", Mode: 0644, Body: "cli"},
+		},
+		check: func(t *testing.T, destDirectory string) {
+			if runtime.GOOS == "windows" {
+				return
+			}
+			for name, want := range map[string]fs.FileMode{
+				"node":                             0755,
+				filepath.Join("package", "cli.js"): 0644,
+			} {
+				fileInfo, err := os.Stat(filepath.Join(destDirectory, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fileInfo.Mode().Perm() != want {
+					t.Errorf("%s: got mode %s, want %s", name, fileInfo.Mode().Perm(), want)
+				}
+			}
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if tt.symlinks && runtime.GOOS == "windows" {
+				t.Skip("symlinks need extra privileges on Windows")
+			}
+			zipFilePath := writeTestZip(t, tt.entries)
+			parentDirectory := t.TempDir()
+			destDirectory := filepath.Join(parentDirectory, "driver")
+			err := extractZip(context.Background(), zipFilePath, destDirectory, func(*zip.File, int, int) {})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got nil error, want an error")
+				}
+				if _, err := os.Lstat(destDirectory); err == nil {
+					t.Error("destination directory was not removed after a failed extraction")
+				}
+				dirEntries, err := os.ReadDir(parentDirectory)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, dirEntry := range dirEntries {
+					t.Errorf("%s was written outside the destination directory", dirEntry.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.check != nil {
+				tt.check(t, destDirectory)
+			}
+		})
+	}
+}
+
+func TestInstallDriverArchiveFailureKeepsDriver(t *testing.T) {
+	driverDirectory := filepath.Join(t.TempDir(), "1.57.0")
+	for directory, content := range map[string]string{
+		driverDirectory:               "current",
+		driverDirectory + ".previous": "previous",
+	} {
+		err := os.Mkdir(directory, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(directory, "marker"), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend := &Backend{
+		PlaywrightRunOptions: &playwright.RunOptions{DriverDirectory: driverDirectory},
+		PlaywrightDriver:     &playwright.PlaywrightDriver{Version: "1.57.0"},
+	}
+	zipFilePath := writeTestZip(t, []testZipEntry{
+		{Name: "package/cli.js", Mode: 0644, Body: "cli"},
+		{Name: "../escaped", Mode: 0644, Body: "x"},
+	})
+	err := backend.installDriverArchive(context.Background(), zipFilePath, func(*zip.File, int, int) {})
+	if err == nil {
+		t.Fatal("got nil error, want an error")
+	}
+	for directory, want := range map[string]string{
+		driverDirectory:               "current",
+		driverDirectory + ".previous": "previous",
+	} {
+		b, err := os.ReadFile(filepath.Join(directory, "marker"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", directory, b, want)
+		}
+	}
+	if _, err := os.Lstat(driverDirectory + ".staging"); err == nil {
+		t.Error("staging directory was left behind")
+	}
+}
+
+func TestReadDriverPlatform(t *testing.T) {
+	if driverPlatform() == "" || (runtime.GOOS == "windows" && runtime.GOARCH != "amd64") {
+		t.Skipf("no driver for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	// The test binary is built for this machine, just like the node
+	// executable in the driver for this machine.
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	driverDirectory := t.TempDir()
+	nodeName := "node"
+	if runtime.GOOS == "windows" {
+		nodeName = "node.exe"
+	}
+	srcFile, err := os.Open(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcFile.Close()
+	destFile, err := os.Create(filepath.Join(driverDirectory, nodeName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(destFile, srcFile)
+	destFile.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform, err := readDriverPlatform(driverDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if platform != driverPlatform() {
+		t.Errorf("got platform %q, want %q", platform, driverPlatform())
+	}
+	_, err = readDriverPlatform(t.TempDir())
+	if err == nil {
+		t.Error("got nil error for a driver without node, want an error")
+	}
+}
+
+func TestCopyDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+	srcDirectory := filepath.Join(t.TempDir(), "chromium-1200")
+	err := os.MkdirAll(filepath.Join(srcDirectory, "chrome-linux"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(srcDirectory, "chrome-linux", "chrome"), []byte("chrome"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(filepath.Join("chrome-linux", "chrome"), filepath.Join(srcDirectory, "chrome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	destDirectory := filepath.Join(t.TempDir(), "chromium-1200")
+	err = copyDirectory(srcDirectory, destDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileInfo, err := os.Stat(filepath.Join(destDirectory, "chrome-linux", "chrome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.Mode().Perm() != 0755 {
+		t.Errorf("got mode %s, want %s", fileInfo.Mode().Perm(), os.FileMode(0755))
+	}
+	target, err := os.Readlink(filepath.Join(destDirectory, "chrome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != filepath.Join("chrome-linux", "chrome") {
+		t.Errorf("got symlink target %q, want %q", target, filepath.Join("chrome-linux", "chrome"))
+	}
+	b, err := os.ReadFile(filepath.Join(destDirectory, "chrome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "chrome" {
+		t.Errorf("got %q, want %q", b, "chrome")
+	}
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"bytes"
+	"changeme/stacktrace"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Flow is an ordered list of steps that is run against a tab. Flows are
+// loaded from .json, .yaml or .yml files in the flows directory and are
+// identified by their file name (without the extension).
+type Flow struct {
+	Name        string     `json:"name" yaml:"-"`
+	Description string     `json:"description" yaml:"description"`
+	Steps       []FlowStep `json:"steps" yaml:"steps"`
+
+	// Error is set by ListFlows if the file could not be loaded, the flow
+	// has no steps then.
+	Error string `json:"error,omitempty" yaml:"-"`
+}
+
+// FlowStep is a single action in a flow.
+//
+//	goto:       URL
+//	click:      Selector
+//	fill:       Selector, Value
+//	select:     Selector, Value (the option value or label)
+//	wait-for:   Selector or URL
+//	press:      Value (the key, e.g. "Enter"), optionally Selector
+//	extract:    Selector, Name, optionally Attribute (defaults to the text content)
+//	screenshot: optionally Selector and Name
+type FlowStep struct {
+	Action    string `json:"action" yaml:"action"`
+	Selector  string `json:"selector,omitempty" yaml:"selector,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	Value     string `json:"value,omitempty" yaml:"value,omitempty"`
+	Attribute string `json:"attribute,omitempty" yaml:"attribute,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Timeout is in milliseconds. If zero, defaultFlowStepTimeout is used.
+	Timeout float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Validate checks that the step has the fields its action needs.
+func (step FlowStep) Validate() error {
+	switch step.Action {
+	case "goto":
+		if step.URL == "" {
+			return fmt.Errorf("goto: missing url")
+		}
+	case "click":
+		if step.Selector == "" {
+			return fmt.Errorf("click: missing selector")
+		}
+	case "fill", "select":
+		if step.Selector == "" {
+			return fmt.Errorf("%s: missing selector", step.Action)
+		}
+	case "wait-for":
+		if step.Selector == "" && step.URL == "" {
+			return fmt.Errorf("wait-for: missing selector or url")
+		}
+	case "press":
+		if step.Value == "" {
+			return fmt.Errorf("press: missing value")
+		}
+	case "extract":
+		if step.Selector == "" {
+			return fmt.Errorf("extract: missing selector")
+		}
+		if step.Name == "" {
+			return fmt.Errorf("extract: missing name")
+		}
+	case "screenshot":
+		// The name becomes part of the screenshot's file name.
+		if step.Name != "" && (!filepath.IsLocal(step.Name) || strings.ContainsAny(step.Name, `/\`) || strings.Contains(step.Name, "..")) {
+			return fmt.Errorf("screenshot: invalid name %q", step.Name)
+		}
+	case "":
+		return fmt.Errorf("missing action")
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+	if step.Timeout < 0 {
+		return fmt.Errorf("%s: invalid timeout %v", step.Action, step.Timeout)
+	}
+	return nil
+}
+
+var flowExtensions = []string{".json", ".yaml", ".yml"}
+
+// reservedFlowNames can't be used as flow names, they clash with the routes
+// under /flow/.
+var reservedFlowNames = []string{"runs"}
+
+// LoadFlow reads and validates a flow file.
+func LoadFlow(filePath string) (Flow, error) {
+	var flow Flow
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return flow, err
+	}
+	ext := filepath.Ext(filePath)
+	if ext == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&flow)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&flow)
+		if errors.Is(err, io.EOF) {
+			// An empty file, reported as a flow without steps below.
+			err = nil
+		}
+	}
+	if err != nil {
+		return flow, fmt.Errorf("%s: %w", filePath, err)
+	}
+	// Name and Error don't come from the file.
+	flow.Name = strings.TrimSuffix(filepath.Base(filePath), ext)
+	flow.Error = ""
+	if slices.Contains(reservedFlowNames, flow.Name) {
+		return flow, fmt.Errorf("%s: the flow name %q is reserved, rename the file", filePath, flow.Name)
+	}
+	if len(flow.Steps) == 0 {
+		return flow, fmt.Errorf("%s: flow has no steps", filePath)
+	}
+	for i, step := range flow.Steps {
+		err := step.Validate()
+		if err != nil {
+			return flow, fmt.Errorf("%s: step %d: %w", filePath, i+1, err)
+		}
+	}
+	return flow, nil
+}
+
+// ListFlows returns the flows in the flows directory. Flows that can't be
+// loaded are listed with only their Name and Error.
+func (backend *Backend) ListFlows() ([]Flow, error) {
+	dirEntries, err := os.ReadDir(backend.FlowsDirectory)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []Flow{}, nil
+		}
+		return nil, stacktrace.New(err)
+	}
+	flows := make([]Flow, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !slices.Contains(flowExtensions, filepath.Ext(dirEntry.Name())) {
+			continue
+		}
+		flow, err := LoadFlow(filepath.Join(backend.FlowsDirectory, dirEntry.Name()))
+		if err != nil {
+			flow = Flow{
+				Name:  strings.TrimSuffix(dirEntry.Name(), filepath.Ext(dirEntry.Name())),
+				Error: err.Error(),
+			}
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+// Flow returns the flow with the given name.
+func (backend *Backend) Flow(name string) (Flow, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return Flow{}, fmt.Errorf("invalid flow name %q", name)
+	}
+	for _, ext := range flowExtensions {
+		flow, err := LoadFlow(filepath.Join(backend.FlowsDirectory, name+ext))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return Flow{}, err
+		}
+		return flow, nil
+	}
+	return Flow{}, fmt.Errorf("no such flow: %s", name)
+}
+
+// FlowRun is the state of a flow being run (or that has been run) against a
+// tab.
+type FlowRun struct {
+	RunID      string            `json:"runID"`
+	ProcessID  string            `json:"processID"`
+	FlowName   string            `json:"flowName"`
+	TabID      int64             `json:"tabID"`
+	Status     string            `json:"status"` // running|succeeded|failed|cancelled
+	Steps      []FlowStepResult  `json:"steps"`
+	Outputs    map[string]string `json:"outputs"`
+	Error      string            `json:"error"`
+	StartedAt  int64             `json:"startedAt"`
+	FinishedAt int64             `json:"finishedAt"`
+}
+
+// FlowStepResult is the state of a single step of a FlowRun.
+type FlowStepResult struct {
+	Step    FlowStep `json:"step"`
+	Status  string   `json:"status"` // pending|running|succeeded|failed|cancelled|skipped
+	Message string   `json:"message"`
+}
+
+// StartFlow starts running a flow against a tab in the background and returns
+// the initial state of the run. Each step is reported to the calling window's
+// status bar.
+func (backend *Backend) StartFlow(ctx context.Context, name string, tabID int64) (FlowRun, error) {
+	return backend.startFlow(windowName(ctx), name, tabID)
+}
+
+func (backend *Backend) startFlow(windowName string, name string, tabID int64) (FlowRun, error) {
+	flow, err := backend.Flow(name)
+	if err != nil {
+		return FlowRun{}, err
+	}
+	page, err := backend.page(tabID)
+	if err != nil {
+		return FlowRun{}, err
+	}
+	flowRun := &FlowRun{
+		RunID:     "flow-" + strconv.FormatInt(backend.Sequence.Add(1), 10),
+		FlowName:  flow.Name,
+		TabID:     tabID,
+		Status:    "running",
+		Steps:     make([]FlowStepResult, len(flow.Steps)),
+		Outputs:   make(map[string]string),
+		StartedAt: time.Now().Unix(),
+	}
+	for i, step := range flow.Steps {
+		flowRun.Steps[i] = FlowStepResult{Step: step, Status: "pending"}
+	}
+	// The run outlives the call that started it, it can only be stopped
+	// with CancelProcess.
+	process := backend.beginProcess(context.Background(), windowName, fmt.Sprintf("Running flow %s", flow.Name), len(flow.Steps))
+	flowRun.ProcessID = process.ProcessID()
+	backend.Mutex.Lock()
+	backend.FlowRuns[flowRun.RunID] = flowRun
+	backend.pruneFlowRuns()
+	snapshot := flowRun.clone()
+	backend.Mutex.Unlock()
+	go backend.runFlow(process, flowRun, page)
+	return snapshot, nil
+}
+
+// maxFlowRuns is the number of flow runs kept in Backend.FlowRuns. Beyond it
+// the oldest finished runs are forgotten.
+const maxFlowRuns = 100
+
+// pruneFlowRuns forgets the oldest finished flow runs beyond maxFlowRuns.
+// Running flows are always kept. Backend.Mutex must be held.
+func (backend *Backend) pruneFlowRuns() {
+	if len(backend.FlowRuns) <= maxFlowRuns {
+		return
+	}
+	var finished []*FlowRun
+	for _, flowRun := range backend.FlowRuns {
+		if flowRun.Status != "running" {
+			finished = append(finished, flowRun)
+		}
+	}
+	slices.SortFunc(finished, func(a, b *FlowRun) int {
+		return compareFlowRuns(*a, *b)
+	})
+	for _, flowRun := range finished[:min(len(finished), len(backend.FlowRuns)-maxFlowRuns)] {
+		delete(backend.FlowRuns, flowRun.RunID)
+	}
+}
+
+// compareFlowRuns orders flow runs by the time they were started, oldest
+// first.
+func compareFlowRuns(a, b FlowRun) int {
+	if n := cmp.Compare(a.StartedAt, b.StartedAt); n != 0 {
+		return n
+	}
+	// Run IDs are numbered in sequence.
+	sequenceA, _ := strconv.ParseInt(strings.TrimPrefix(a.RunID, "flow-"), 10, 64)
+	sequenceB, _ := strconv.ParseInt(strings.TrimPrefix(b.RunID, "flow-"), 10, 64)
+	return cmp.Compare(sequenceA, sequenceB)
+}
+
+// FlowRun returns the current state of a flow run.
+func (backend *Backend) FlowRun(runID string) (FlowRun, error) {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	flowRun, ok := backend.FlowRuns[runID]
+	if !ok {
+		return FlowRun{}, fmt.Errorf("no such flow run: %s", runID)
+	}
+	return flowRun.clone(), nil
+}
+
+// ListFlowRuns returns the flow runs, most recent first. Only the last
+// maxFlowRuns finished runs are kept.
+func (backend *Backend) ListFlowRuns() []FlowRun {
+	backend.Mutex.Lock()
+	flowRuns := make([]FlowRun, 0, len(backend.FlowRuns))
+	for _, flowRun := range backend.FlowRuns {
+		flowRuns = append(flowRuns, flowRun.clone())
+	}
+	backend.Mutex.Unlock()
+	slices.SortFunc(flowRuns, func(a, b FlowRun) int {
+		return compareFlowRuns(b, a)
+	})
+	return flowRuns
+}
+
+// clone returns a copy of the flow run that is safe to use without holding
+// Backend.Mutex.
+func (flowRun *FlowRun) clone() FlowRun {
+	snapshot := *flowRun
+	snapshot.Steps = slices.Clone(flowRun.Steps)
+	snapshot.Outputs = make(map[string]string, len(flowRun.Outputs))
+	for name, value := range flowRun.Outputs {
+		snapshot.Outputs[name] = value
+	}
+	return snapshot
+}
+
+// runFlow executes the steps of a flow run one by one, stopping at the first
+// failure or when the process is cancelled.
+func (backend *Backend) runFlow(process *Process, flowRun *FlowRun, page playwright.Page) {
+	ctx := process.Context()
+	type stepResult struct {
+		output string
+		err    error
+	}
+	for i := range flowRun.Steps {
+		backend.Mutex.Lock()
+		flowRun.Steps[i].Status = "running"
+		step := flowRun.Steps[i].Step
+		backend.Mutex.Unlock()
+		process.SetProgress(fmt.Sprintf("%s: step %d/%d %s", flowRun.FlowName, i+1, len(flowRun.Steps), step.Action), i, len(flowRun.Steps))
+		var output string
+		err := ctx.Err()
+		if err == nil {
+			resultChan := make(chan stepResult, 1)
+			go func() {
+				output, err := backend.runFlowStep(ctx, flowRun, i, step, page)
+				resultChan <- stepResult{output: output, err: err}
+			}()
+			var result stepResult
+			select {
+			case <-ctx.Done():
+				// Playwright calls can't be interrupted. Wait for the
+				// step to return, which its timeout bounds, so that
+				// nothing acts on the page once the run is reported as
+				// cancelled.
+				process.SetMessage(fmt.Sprintf("%s: cancelling, waiting for step %d/%d %s to return", flowRun.FlowName, i+1, len(flowRun.Steps), step.Action))
+				result = <-resultChan
+				if result.err != nil {
+					result.err = ctx.Err()
+				}
+			case result = <-resultChan:
+			}
+			output, err = result.output, result.err
+		}
+		backend.Mutex.Lock()
+		if err != nil {
+			status := "failed"
+			if errors.Is(err, context.Canceled) {
+				status = "cancelled"
+			}
+			flowRun.Steps[i].Status = status
+			flowRun.Steps[i].Message = err.Error()
+			for j := i + 1; j < len(flowRun.Steps); j++ {
+				flowRun.Steps[j].Status = "skipped"
+			}
+			flowRun.Status = status
+			flowRun.Error = fmt.Sprintf("step %d (%s): %v", i+1, step.Action, err)
+			flowRun.FinishedAt = time.Now().Unix()
+			backend.Mutex.Unlock()
+			process.Fail(fmt.Errorf("%s: step %d (%s): %w", flowRun.FlowName, i+1, step.Action, err))
+			return
+		}
+		flowRun.Steps[i].Status = "succeeded"
+		flowRun.Steps[i].Message = output
+		if step.Action == "extract" {
+			flowRun.Outputs[step.Name] = output
+		}
+		backend.Mutex.Unlock()
+	}
+	backend.Mutex.Lock()
+	flowRun.Status = "succeeded"
+	flowRun.FinishedAt = time.Now().Unix()
+	backend.Mutex.Unlock()
+	process.Finish(fmt.Sprintf("%s: succeeded", flowRun.FlowName))
+}
+
+// defaultFlowStepTimeout is the timeout in milliseconds of steps that don't
+// set their own. It bounds how long cancelling a run takes.
+const defaultFlowStepTimeout = 30000
+
+// flowStepTimeout returns the Playwright timeout for a step: its own timeout
+// or defaultFlowStepTimeout, cut short by the deadline of ctx if there is one.
+func flowStepTimeout(ctx context.Context, step FlowStep) *float64 {
+	timeout := step.Timeout
+	if timeout <= 0 {
+		timeout = defaultFlowStepTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// Zero would mean no timeout at all.
+		timeout = min(timeout, max(float64(time.Until(deadline).Milliseconds()), 1))
+	}
+	return playwright.Float(timeout)
+}
+
+// runFlowStep executes a single step and returns a short description of its
+// result (for extract, the extracted value).
+func (backend *Backend) runFlowStep(ctx context.Context, flowRun *FlowRun, index int, step FlowStep, page playwright.Page) (string, error) {
+	timeout := flowStepTimeout(ctx, step)
+	switch step.Action {
+	case "goto":
+		_, err := page.Goto(step.URL, playwright.PageGotoOptions{Timeout: timeout})
+		if err != nil {
+			return "", err
+		}
+		return page.URL(), nil
+	case "click":
+		return "", page.Locator(step.Selector).Click(playwright.LocatorClickOptions{Timeout: timeout})
+	case "fill":
+		return "", page.Locator(step.Selector).Fill(step.Value, playwright.LocatorFillOptions{Timeout: timeout})
+	case "select":
+		selected, err := page.Locator(step.Selector).SelectOption(playwright.SelectOptionValues{
+			ValuesOrLabels: &[]string{step.Value},
+		}, playwright.LocatorSelectOptionOptions{Timeout: timeout})
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(selected, ", "), nil
+	case "wait-for":
+		if step.Selector != "" {
+			return "", page.Locator(step.Selector).WaitFor(playwright.LocatorWaitForOptions{
+				State:   playwright.WaitForSelectorStateVisible,
+				Timeout: timeout,
+			})
+		}
+		return "", page.WaitForURL(step.URL, playwright.PageWaitForURLOptions{Timeout: timeout})
+	case "press":
+		if step.Selector != "" {
+			return "", page.Locator(step.Selector).Press(step.Value, playwright.LocatorPressOptions{Timeout: timeout})
+		}
+		return "", page.Keyboard().Press(step.Value)
+	case "extract":
+		if step.Attribute != "" {
+			return page.Locator(step.Selector).GetAttribute(step.Attribute, playwright.LocatorGetAttributeOptions{Timeout: timeout})
+		}
+		return page.Locator(step.Selector).TextContent(playwright.LocatorTextContentOptions{Timeout: timeout})
+	case "screenshot":
+		name := step.Name
+		if name == "" {
+			name = "step" + strconv.Itoa(index+1)
+		}
+		screenshotPath := filepath.Join(backend.FlowsDirectory, "screenshots", flowRun.RunID+"-"+name+".png")
+		err := os.MkdirAll(filepath.Dir(screenshotPath), 0755)
+		if err != nil {
+			return "", err
+		}
+		if step.Selector != "" {
+			_, err = page.Locator(step.Selector).Screenshot(playwright.LocatorScreenshotOptions{
+				Path:    playwright.String(screenshotPath),
+				Timeout: timeout,
+			})
+		} else {
+			_, err = page.Screenshot(playwright.PageScreenshotOptions{
+				Path:     playwright.String(screenshotPath),
+				FullPage: playwright.Bool(true),
+				Timeout:  timeout,
+			})
+		}
+		if err != nil {
+			return "", err
+		}
+		return screenshotPath, nil
+	default:
+		return "", fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+// flow serves the flow endpoints:
+//
+//	GET  /flow/               lists the flows
+//	POST /flow/               starts a flow (form fields: name, tabID, windowName)
+//	GET  /flow/<name>/        returns a flow definition
+//	GET  /flow/runs/          lists the flow runs
+//	GET  /flow/runs/<runID>/  returns the state of a flow run
+func (backend *Backend) flow(w http.ResponseWriter, r *http.Request, pathTail string) {
+	writeResponse := func(w http.ResponseWriter, r *http.Request, statusCode int, response any) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(statusCode)
+		if r.Method == "HEAD" {
+			return
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(response)
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+	name, runID, _ := strings.Cut(pathTail, "/")
+	if r.Method == "POST" {
+		if pathTail != "" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		tabID, err := strconv.ParseInt(r.Form.Get("tabID"), 10, 64)
+		if err != nil {
+			http.Error(w, "InvalidTabID", http.StatusBadRequest)
+			return
+		}
+		flowRun, err := backend.startFlow(r.Form.Get("windowName"), r.Form.Get("name"), tabID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeResponse(w, r, http.StatusAccepted, flowRun)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case pathTail == "":
+		flows, err := backend.ListFlows()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, r, http.StatusOK, flows)
+	case name == "runs" && runID == "":
+		writeResponse(w, r, http.StatusOK, backend.ListFlowRuns())
+	case name == "runs":
+		flowRun, err := backend.FlowRun(runID)
+		if err != nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		writeResponse(w, r, http.StatusOK, flowRun)
+	case runID == "":
+		flow, err := backend.Flow(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeResponse(w, r, http.StatusOK, flow)
+	default:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFlowStepValidateScreenshotName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "", wantErr: false},
+		{name: "checkout", wantErr: false},
+		{name: "step 2.final", wantErr: false},
+		{name: "../../x", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "a..b", wantErr: true},
+		{name: "screenshots/x", wantErr: true},
+		{name: `screenshots\x`, wantErr: true},
+		{name: "/tmp/x", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := FlowStep{Action: "screenshot", Name: tt.name}.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
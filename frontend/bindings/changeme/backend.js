@@ -10,6 +10,86 @@ import { Call as $Call, CancellablePromise as $CancellablePromise, Create as $Cr
 // @ts-ignore: Unused imports
 import * as $models from "./models.js";
 
+/**
+ * ActivateTab brings a tab to the front of its browser window.
+ * @param {number} tabID
+ * @returns {$CancellablePromise<void>}
+ */
+export function ActivateTab(tabID) {
+    return $Call.ByID(1182909536, tabID);
+}
+
+/**
+ * CDPPort returns the remote debugging port configured for a profile. Zero
+ * means the port is picked automatically.
+ * @param {string} profileName
+ * @returns {$CancellablePromise<number>}
+ */
+export function CDPPort(profileName) {
+    return $Call.ByID(1563233342, profileName);
+}
+
+/**
+ * CancelProcess cancels a running process. The process reports itself as
+ * cancelled once the operation has stopped.
+ * @param {string} processID
+ * @returns {$CancellablePromise<void>}
+ */
+export function CancelProcess(processID) {
+    return $Call.ByID(3882627695, processID);
+}
+
+/**
+ * CheckNetworkSettings makes a request to the driver download host with the
+ * given (not necessarily saved) network settings, so that they can be tried
+ * out before saving them.
+ * @param {$models.NetworkSettings} settings
+ * @returns {$CancellablePromise<void>}
+ */
+export function CheckNetworkSettings(settings) {
+    return $Call.ByID(2430522211, settings);
+}
+
+/**
+ * ChooseDirectory asks the user to pick a directory and returns its path, or
+ * an empty string if the dialog was cancelled.
+ * @param {string} title
+ * @param {string} directory
+ * @returns {$CancellablePromise<string>}
+ */
+export function ChooseDirectory(title, directory) {
+    return $Call.ByID(85044300, title, directory);
+}
+
+/**
+ * CleanupDrivers removes the driver versions that are neither required nor
+ * pinned (including their staging and previous directories), downloaded
+ * archives and partial downloads for those versions, and leftovers from
+ * interrupted imports. The previous version of the required driver is kept
+ * for RollbackDriver. Only directories that hold a driver of the version they
+ * are named after, or are the staging, previous or rollback directory of a
+ * version, are removed. Anything else in the drivers directory is left alone.
+ * @returns {$CancellablePromise<$models.DriverCleanup>}
+ */
+export function CleanupDrivers() {
+    return $Call.ByID(827102235).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType0($result);
+    }));
+}
+
+/**
+ * CloneProfile copies an existing profile (including its logged-in sessions)
+ * into a new profile. The clone always picks its CDP port automatically so
+ * that it can run side by side with the original. The source profile must
+ * not be in use.
+ * @param {string} srcName
+ * @param {string} destName
+ * @returns {$CancellablePromise<void>}
+ */
+export function CloneProfile(srcName, destName) {
+    return $Call.ByID(2626228974, srcName, destName);
+}
+
 /**
  * @returns {$CancellablePromise<void>}
  */
@@ -17,6 +97,26 @@ export function Close() {
     return $Call.ByID(1627081596);
 }
 
+/**
+ * CloseBrowser closes the browser session of a profile without reconnecting
+ * it. Browsers launched with LaunchModePersistent are closed, CDP sessions are
+ * only disconnected and leave the browser running.
+ * @param {string} profileName
+ * @returns {$CancellablePromise<void>}
+ */
+export function CloseBrowser(profileName) {
+    return $Call.ByID(3247811588, profileName);
+}
+
+/**
+ * CloseTab closes a tab.
+ * @param {number} tabID
+ * @returns {$CancellablePromise<void>}
+ */
+export function CloseTab(tabID) {
+    return $Call.ByID(405822011, tabID);
+}
+
 /**
  * @param {string} name
  * @returns {$CancellablePromise<void>}
@@ -25,6 +125,24 @@ export function CloseWindow(name) {
     return $Call.ByID(2212387040, name);
 }
 
+/**
+ * ControlAPIToken returns the token that clients of the control API must
+ * send.
+ * @returns {$CancellablePromise<string>}
+ */
+export function ControlAPIToken() {
+    return $Call.ByID(1906253396);
+}
+
+/**
+ * CreateProfile creates a new empty profile.
+ * @param {string} name
+ * @returns {$CancellablePromise<void>}
+ */
+export function CreateProfile(name) {
+    return $Call.ByID(1973986713, name);
+}
+
 /**
  * @param {$models.WebviewWindowOptions} options
  * @returns {$CancellablePromise<void>}
@@ -33,6 +151,16 @@ export function CreateWindow(options) {
     return $Call.ByID(544970000, options);
 }
 
+/**
+ * DeleteProfile deletes a profile and everything in it. The profile must not
+ * be in use.
+ * @param {string} name
+ * @returns {$CancellablePromise<void>}
+ */
+export function DeleteProfile(name) {
+    return $Call.ByID(3537171932, name);
+}
+
 /**
  * @param {$models.MessageDialogOptions} options
  * @returns {$CancellablePromise<void>}
@@ -50,6 +178,41 @@ export function EnableWindow(name, enabled) {
     return $Call.ByID(3477006231, name, enabled);
 }
 
+/**
+ * ExportDriverBundle writes a driver bundle for this machine's platform to
+ * destFilePath, for importing with ImportDriver on machines without internet
+ * access. If includeBrowsers is set, the Playwright browser builds in
+ * PLAYWRIGHT_BROWSERS_PATH are included as well.
+ * @param {string} destFilePath
+ * @param {boolean} includeBrowsers
+ * @returns {$CancellablePromise<void>}
+ */
+export function ExportDriverBundle(destFilePath, includeBrowsers) {
+    return $Call.ByID(105781160, destFilePath, includeBrowsers);
+}
+
+/**
+ * Flow returns the flow with the given name.
+ * @param {string} name
+ * @returns {$CancellablePromise<$models.Flow>}
+ */
+export function Flow(name) {
+    return $Call.ByID(77335920, name).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType1($result);
+    }));
+}
+
+/**
+ * FlowRun returns the current state of a flow run.
+ * @param {string} runID
+ * @returns {$CancellablePromise<$models.FlowRun>}
+ */
+export function FlowRun(runID) {
+    return $Call.ByID(209801637, runID).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType2($result);
+    }));
+}
+
 /**
  * @param {string} name
  * @returns {$CancellablePromise<void>}
@@ -58,6 +221,16 @@ export function FocusWindow(name) {
     return $Call.ByID(711930652, name);
 }
 
+/**
+ * GetSettings returns the saved and the effective settings.
+ * @returns {$CancellablePromise<$models.SettingsInfo>}
+ */
+export function GetSettings() {
+    return $Call.ByID(1706063129).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType3($result);
+    }));
+}
+
 /**
  * @returns {$CancellablePromise<string>}
  */
@@ -66,10 +239,223 @@ export function Hello() {
 }
 
 /**
+ * ImportDriver installs the driver from a local file, which is either a
+ * driver archive (playwright-<version>-<platform>.zip) or a driver bundle
+ * created with ExportDriverBundle. The driver version and platform must match
+ * the ones we need. Browser builds in a bundle are installed into
+ * PLAYWRIGHT_BROWSERS_PATH unless already present. The driver archive must
+ * match the checksum manifest, if no checksum is known for it or it was
+ * repacked it is only installed if allowUnverified is set.
+ * @param {string} filePath
+ * @param {boolean} allowUnverified
  * @returns {$CancellablePromise<void>}
  */
-export function OpenBrowser() {
-    return $Call.ByID(502492056);
+export function ImportDriver(filePath, allowUnverified) {
+    return $Call.ByID(3752684623, filePath, allowUnverified);
+}
+
+/**
+ * ListBrowserLaunchers returns the browsers available on the machine.
+ * @returns {$CancellablePromise<$models.BrowserLauncher[]>}
+ */
+export function ListBrowserLaunchers() {
+    return $Call.ByID(2184493169).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType5($result);
+    }));
+}
+
+/**
+ * ListBrowserSessions returns the open browser sessions, ordered by profile
+ * name.
+ * @returns {$CancellablePromise<$models.BrowserSessionInfo[]>}
+ */
+export function ListBrowserSessions() {
+    return $Call.ByID(628685397).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType7($result);
+    }));
+}
+
+/**
+ * ListDrivers returns the installed driver versions and downloaded archives
+ * with their disk usage.
+ * @returns {$CancellablePromise<$models.DriverInventory>}
+ */
+export function ListDrivers() {
+    return $Call.ByID(1236647303).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType8($result);
+    }));
+}
+
+/**
+ * ListFlowRuns returns the flow runs, most recent first. Only the last
+ * maxFlowRuns finished runs are kept.
+ * @returns {$CancellablePromise<$models.FlowRun[]>}
+ */
+export function ListFlowRuns() {
+    return $Call.ByID(163715386).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType9($result);
+    }));
+}
+
+/**
+ * ListFlows returns the flows in the flows directory. Flows that can't be
+ * loaded are listed with only their Name and Error.
+ * @returns {$CancellablePromise<$models.Flow[]>}
+ */
+export function ListFlows() {
+    return $Call.ByID(2052302001).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType10($result);
+    }));
+}
+
+/**
+ * ListProcesses returns the state of the running processes, oldest first.
+ * @returns {$CancellablePromise<$models.ProcessUpdate[]>}
+ */
+export function ListProcesses() {
+    return $Call.ByID(2011883889).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType12($result);
+    }));
+}
+
+/**
+ * ListProfiles returns the profiles in the profiles directory.
+ * @returns {$CancellablePromise<$models.Profile[]>}
+ */
+export function ListProfiles() {
+    return $Call.ByID(3297323506).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType14($result);
+    }));
+}
+
+/**
+ * ListTabDetails returns the live state of all tabs, in the order they were
+ * opened.
+ * @returns {$CancellablePromise<$models.TabDetail[]>}
+ */
+export function ListTabDetails() {
+    return $Call.ByID(4202796615).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType16($result);
+    }));
+}
+
+/**
+ * ListTabs returns the tabs open in all connected browsers, in the order they
+ * were opened.
+ * @returns {$CancellablePromise<$models.Tab[]>}
+ */
+export function ListTabs() {
+    return $Call.ByID(1318298516).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType18($result);
+    }));
+}
+
+/**
+ * NewTab opens a new tab in a profile's browser and navigates it to url (if
+ * not empty).
+ * @param {string} profileName
+ * @param {string} url
+ * @returns {$CancellablePromise<$models.Tab>}
+ */
+export function NewTab(profileName, url) {
+    return $Call.ByID(3929090005, profileName, url).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType17($result);
+    }));
+}
+
+/**
+ * OpenBrowser opens a browser for a profile, either by attaching to (and if
+ * necessary launching) a user-visible browser over CDP or by launching
+ * Playwright's own Chromium, Firefox or WebKit build. Browsers for different
+ * profiles can be open at the same time.
+ * @param {$models.OpenBrowserOptions} options
+ * @returns {$CancellablePromise<void>}
+ */
+export function OpenBrowser(options) {
+    return $Call.ByID(502492056, options);
+}
+
+/**
+ * PinDriver pins or unpins an installed driver version. Pinned versions (and
+ * their archives) are kept by CleanupDrivers.
+ * @param {string} version
+ * @param {boolean} pinned
+ * @returns {$CancellablePromise<void>}
+ */
+export function PinDriver(version, pinned) {
+    return $Call.ByID(855886893, version, pinned);
+}
+
+/**
+ * RenameProfile renames a profile. The profile must not be in use.
+ * @param {string} oldName
+ * @param {string} newName
+ * @returns {$CancellablePromise<void>}
+ */
+export function RenameProfile(oldName, newName) {
+    return $Call.ByID(3659103239, oldName, newName);
+}
+
+/**
+ * ResetSettings moves the saved settings aside to settings.json.invalid after a
+ * failed start, so that the next RetryStartup uses the defaults.
+ * @returns {$CancellablePromise<void>}
+ */
+export function ResetSettings() {
+    return $Call.ByID(2661067114);
+}
+
+/**
+ * RetryStartup initialises the backend again after a failed start. If
+ * driversDirectory is not empty it is used as the drivers directory and saved
+ * in the settings. On success the recovery window is replaced by the main
+ * window.
+ * @param {string} driversDirectory
+ * @returns {$CancellablePromise<void>}
+ */
+export function RetryStartup(driversDirectory) {
+    return $Call.ByID(651321327, driversDirectory);
+}
+
+/**
+ * RollbackDriver restores the driver that was replaced by the last install.
+ * Rolling back twice restores the newer driver again. The driver must not be
+ * running.
+ * @returns {$CancellablePromise<void>}
+ */
+export function RollbackDriver() {
+    return $Call.ByID(2146635522);
+}
+
+/**
+ * SelectBrowserLauncher remembers the browser to launch in OpenBrowser.
+ * @param {string} id
+ * @returns {$CancellablePromise<void>}
+ */
+export function SelectBrowserLauncher(id) {
+    return $Call.ByID(3598861928, id);
+}
+
+/**
+ * SelectedBrowserLauncher returns the browser that OpenBrowser will launch.
+ * If the user has not picked one, it is the first browser found.
+ * @returns {$CancellablePromise<$models.BrowserLauncher>}
+ */
+export function SelectedBrowserLauncher() {
+    return $Call.ByID(2769647363).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType4($result);
+    }));
+}
+
+/**
+ * SetCDPPort configures the remote debugging port for a profile. Pass zero to
+ * pick a free port automatically.
+ * @param {string} profileName
+ * @param {number} port
+ * @returns {$CancellablePromise<void>}
+ */
+export function SetCDPPort(profileName, port) {
+    return $Call.ByID(4102460542, profileName, port);
 }
 
 /**
@@ -81,9 +467,77 @@ export function ShowWindow(name, show) {
     return $Call.ByID(392917569, name, show);
 }
 
+/**
+ * StartFlow starts running a flow against a tab in the background and returns
+ * the initial state of the run. Each step is reported to the calling window's
+ * status bar.
+ * @param {string} name
+ * @param {number} tabID
+ * @returns {$CancellablePromise<$models.FlowRun>}
+ */
+export function StartFlow(name, tabID) {
+    return $Call.ByID(1337718892, name, tabID).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType2($result);
+    }));
+}
+
 /**
  * @returns {$CancellablePromise<void>}
  */
 export function StartPlaywright() {
     return $Call.ByID(1677311519);
 }
+
+/**
+ * StartupStatus returns the state of the backend's initialisation.
+ * @returns {$CancellablePromise<$models.StartupStatus>}
+ */
+export function StartupStatus() {
+    return $Call.ByID(2626027643).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType19($result);
+    }));
+}
+
+/**
+ * TabDetail returns the live state of a tab.
+ * @param {number} tabID
+ * @returns {$CancellablePromise<$models.TabDetail>}
+ */
+export function TabDetail(tabID) {
+    return $Call.ByID(3694841334, tabID).then(/** @type {($result: any) => any} */(($result) => {
+        return $$createType15($result);
+    }));
+}
+
+/**
+ * UpdateSettings validates and saves the settings and broadcasts a
+ * SettingsChanged event. The CDP port, download mirrors and network settings
+ * take effect immediately, the directories and the HTTP address after a restart.
+ * @param {$models.Settings} settings
+ * @returns {$CancellablePromise<void>}
+ */
+export function UpdateSettings(settings) {
+    return $Call.ByID(1738562228, settings);
+}
+
+// Private type creation functions
+const $$createType0 = $models.DriverCleanup.createFrom;
+const $$createType1 = $models.Flow.createFrom;
+const $$createType2 = $models.FlowRun.createFrom;
+const $$createType3 = $models.SettingsInfo.createFrom;
+const $$createType4 = $models.BrowserLauncher.createFrom;
+const $$createType5 = $Create.Array($$createType4);
+const $$createType6 = $models.BrowserSessionInfo.createFrom;
+const $$createType7 = $Create.Array($$createType6);
+const $$createType8 = $models.DriverInventory.createFrom;
+const $$createType9 = $Create.Array($$createType2);
+const $$createType10 = $Create.Array($$createType1);
+const $$createType11 = $models.ProcessUpdate.createFrom;
+const $$createType12 = $Create.Array($$createType11);
+const $$createType13 = $models.Profile.createFrom;
+const $$createType14 = $Create.Array($$createType13);
+const $$createType15 = $models.TabDetail.createFrom;
+const $$createType16 = $Create.Array($$createType15);
+const $$createType17 = $models.Tab.createFrom;
+const $$createType18 = $Create.Array($$createType17);
+const $$createType19 = $models.StartupStatus.createFrom;
@@ -8,8 +8,28 @@ export {
 };
 
 export {
+    BrowserLauncher,
+    BrowserSessionInfo,
+    BrowserStatus,
+    DriverArchive,
+    DriverCleanup,
+    DriverInventory,
+    DriverVersion,
+    Flow,
+    FlowRun,
+    FlowStep,
+    FlowStepResult,
     InstallDriverEvent,
     MessageDialogOptions,
+    NetworkSettings,
+    OpenBrowserOptions,
     ProcessUpdate,
+    Profile,
+    Settings,
+    SettingsInfo,
+    StartupStatus,
+    Tab,
+    TabDetail,
+    TabEvent,
     WebviewWindowOptions
 } from "./models.js";
@@ -10,25 +10,135 @@ import { Create as $Create } from "@wailsio/runtime";
 // @ts-ignore: Unused imports
 import * as application$0 from "../github.com/wailsapp/wails/v3/pkg/application/models.js";
 
-export class InstallDriverEvent {
+/**
+ * BrowserLauncher is a Chromium-based browser installed on the machine that
+ * can be launched with a remote debugging port.
+ */
+export class BrowserLauncher {
     /**
-     * Creates a new InstallDriverEvent instance.
-     * @param {Partial<InstallDriverEvent>} [$$source = {}] - The source object to create the InstallDriverEvent.
+     * Creates a new BrowserLauncher instance.
+     * @param {Partial<BrowserLauncher>} [$$source = {}] - The source object to create the BrowserLauncher.
      */
     constructor($$source = {}) {
-        if (!("eventID" in $$source)) {
+        if (!("id" in $$source)) {
             /**
              * @member
              * @type {string}
              */
-            this["eventID"] = "";
+            this["id"] = "";
         }
-        if (!("category" in $$source)) {
+        if (!("name" in $$source)) {
             /**
              * @member
              * @type {string}
              */
-            this["category"] = "";
+            this["name"] = "";
+        }
+        if (!("executablePath" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["executablePath"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new BrowserLauncher instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {BrowserLauncher}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new BrowserLauncher(/** @type {Partial<BrowserLauncher>} */($$parsedSource));
+    }
+}
+
+/**
+ * BrowserSessionInfo describes an open browser session.
+ */
+export class BrowserSessionInfo {
+    /**
+     * Creates a new BrowserSessionInfo instance.
+     * @param {Partial<BrowserSessionInfo>} [$$source = {}] - The source object to create the BrowserSessionInfo.
+     */
+    constructor($$source = {}) {
+        if (!("profileName" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["profileName"] = "";
+        }
+        if (!("launchMode" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["launchMode"] = "";
+        }
+        if (!("browserName" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["browserName"] = "";
+        }
+        if (!("headless" in $$source)) {
+            /**
+             * @member
+             * @type {boolean}
+             */
+            this["headless"] = false;
+        }
+        if (!("connected" in $$source)) {
+            /**
+             * @member
+             * @type {boolean}
+             */
+            this["connected"] = false;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new BrowserSessionInfo instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {BrowserSessionInfo}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new BrowserSessionInfo(/** @type {Partial<BrowserSessionInfo>} */($$parsedSource));
+    }
+}
+
+/**
+ * BrowserStatus is emitted to all windows whenever a profile's browser
+ * connects, disconnects or is being reconnected.
+ */
+export class BrowserStatus {
+    /**
+     * Creates a new BrowserStatus instance.
+     * @param {Partial<BrowserStatus>} [$$source = {}] - The source object to create the BrowserStatus.
+     */
+    constructor($$source = {}) {
+        if (!("profileName" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["profileName"] = "";
+        }
+        if (!("status" in $$source)) {
+            /**
+             * connected|disconnected|reconnecting|failed
+             * @member
+             * @type {string}
+             */
+            this["status"] = "";
         }
         if (!("message" in $$source)) {
             /**
@@ -37,6 +147,13 @@ export class InstallDriverEvent {
              */
             this["message"] = "";
         }
+        if (!("attempt" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["attempt"] = 0;
+        }
         if (!("timestamp" in $$source)) {
             /**
              * @member
@@ -49,112 +166,1390 @@ export class InstallDriverEvent {
     }
 
     /**
-     * Creates a new InstallDriverEvent instance from a string or object.
+     * Creates a new BrowserStatus instance from a string or object.
      * @param {any} [$$source = {}]
-     * @returns {InstallDriverEvent}
+     * @returns {BrowserStatus}
      */
     static createFrom($$source = {}) {
         let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
-        return new InstallDriverEvent(/** @type {Partial<InstallDriverEvent>} */($$parsedSource));
+        return new BrowserStatus(/** @type {Partial<BrowserStatus>} */($$parsedSource));
     }
 }
 
-export class MessageDialogOptions {
+/**
+ * DriverArchive is a downloaded driver archive (or a leftover partial
+ * download) in Backend.DownloadsDirectory.
+ */
+export class DriverArchive {
     /**
-     * Creates a new MessageDialogOptions instance.
-     * @param {Partial<MessageDialogOptions>} [$$source = {}] - The source object to create the MessageDialogOptions.
+     * Creates a new DriverArchive instance.
+     * @param {Partial<DriverArchive>} [$$source = {}] - The source object to create the DriverArchive.
      */
     constructor($$source = {}) {
-        if (!("DialogType" in $$source)) {
+        if (!("name" in $$source)) {
             /**
-             * Info|Question|Warning|Error
              * @member
              * @type {string}
              */
-            this["DialogType"] = "";
+            this["name"] = "";
         }
-        if (!("Title" in $$source)) {
+        if (!("filePath" in $$source)) {
             /**
              * @member
              * @type {string}
              */
-            this["Title"] = "";
+            this["filePath"] = "";
         }
-        if (!("Message" in $$source)) {
+        if (!("size" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["size"] = 0;
+        }
+        if (!("sizeText" in $$source)) {
             /**
              * @member
              * @type {string}
              */
-            this["Message"] = "";
+            this["sizeText"] = "";
+        }
+        if (!("stale" in $$source)) {
+            /**
+             * @member
+             * @type {boolean}
+             */
+            this["stale"] = false;
         }
 
         Object.assign(this, $$source);
     }
 
     /**
-     * Creates a new MessageDialogOptions instance from a string or object.
+     * Creates a new DriverArchive instance from a string or object.
      * @param {any} [$$source = {}]
-     * @returns {MessageDialogOptions}
+     * @returns {DriverArchive}
      */
     static createFrom($$source = {}) {
         let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
-        return new MessageDialogOptions(/** @type {Partial<MessageDialogOptions>} */($$parsedSource));
+        return new DriverArchive(/** @type {Partial<DriverArchive>} */($$parsedSource));
     }
 }
 
-export class ProcessUpdate {
+/**
+ * DriverCleanup is the result of CleanupDrivers.
+ */
+export class DriverCleanup {
     /**
-     * Creates a new ProcessUpdate instance.
-     * @param {Partial<ProcessUpdate>} [$$source = {}] - The source object to create the ProcessUpdate.
+     * Creates a new DriverCleanup instance.
+     * @param {Partial<DriverCleanup>} [$$source = {}] - The source object to create the DriverCleanup.
      */
     constructor($$source = {}) {
-        if (!("processID" in $$source)) {
+        if (!("removed" in $$source)) {
+            /**
+             * @member
+             * @type {string[]}
+             */
+            this["removed"] = [];
+        }
+        if (!("freed" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["freed"] = 0;
+        }
+        if (!("freedText" in $$source)) {
             /**
              * @member
              * @type {string}
              */
-            this["processID"] = "";
+            this["freedText"] = "";
         }
-        if (!("message" in $$source)) {
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new DriverCleanup instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {DriverCleanup}
+     */
+    static createFrom($$source = {}) {
+        const $$createField0_0 = $$createType0;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("removed" in $$parsedSource) {
+            $$parsedSource["removed"] = $$createField0_0($$parsedSource["removed"]);
+        }
+        return new DriverCleanup(/** @type {Partial<DriverCleanup>} */($$parsedSource));
+    }
+}
+
+/**
+ * DriverInventory lists the installed driver versions and downloaded
+ * archives.
+ */
+export class DriverInventory {
+    /**
+     * Creates a new DriverInventory instance.
+     * @param {Partial<DriverInventory>} [$$source = {}] - The source object to create the DriverInventory.
+     */
+    constructor($$source = {}) {
+        if (!("requiredVersion" in $$source)) {
             /**
              * @member
              * @type {string}
              */
-            this["message"] = "";
+            this["requiredVersion"] = "";
         }
-        if (!("progressValue" in $$source)) {
+        if (!("drivers" in $$source)) {
             /**
              * @member
-             * @type {number}
+             * @type {DriverVersion[]}
              */
-            this["progressValue"] = 0;
+            this["drivers"] = [];
         }
-        if (!("progressMax" in $$source)) {
+        if (!("archives" in $$source)) {
+            /**
+             * @member
+             * @type {DriverArchive[]}
+             */
+            this["archives"] = [];
+        }
+        if (!("totalSize" in $$source)) {
             /**
              * @member
              * @type {number}
              */
-            this["progressMax"] = 0;
+            this["totalSize"] = 0;
         }
-        if (!("timestamp" in $$source)) {
+        if (!("totalSizeText" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["totalSizeText"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new DriverInventory instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {DriverInventory}
+     */
+    static createFrom($$source = {}) {
+        const $$createField1_0 = $$createType2;
+        const $$createField2_0 = $$createType4;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("drivers" in $$parsedSource) {
+            $$parsedSource["drivers"] = $$createField1_0($$parsedSource["drivers"]);
+        }
+        if ("archives" in $$parsedSource) {
+            $$parsedSource["archives"] = $$createField2_0($$parsedSource["archives"]);
+        }
+        return new DriverInventory(/** @type {Partial<DriverInventory>} */($$parsedSource));
+    }
+}
+
+/**
+ * DriverVersion is an installed driver version.
+ */
+export class DriverVersion {
+    /**
+     * Creates a new DriverVersion instance.
+     * @param {Partial<DriverVersion>} [$$source = {}] - The source object to create the DriverVersion.
+     */
+    constructor($$source = {}) {
+        if (!("version" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["version"] = "";
+        }
+        if (!("directory" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["directory"] = "";
+        }
+        if (!("size" in $$source)) {
             /**
              * @member
              * @type {number}
              */
-            this["timestamp"] = 0;
+            this["size"] = 0;
+        }
+        if (!("sizeText" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["sizeText"] = "";
+        }
+        if (!("pinned" in $$source)) {
+            /**
+             * @member
+             * @type {boolean}
+             */
+            this["pinned"] = false;
+        }
+        if (!("required" in $$source)) {
+            /**
+             * @member
+             * @type {boolean}
+             */
+            this["required"] = false;
         }
 
         Object.assign(this, $$source);
     }
 
     /**
-     * Creates a new ProcessUpdate instance from a string or object.
+     * Creates a new DriverVersion instance from a string or object.
      * @param {any} [$$source = {}]
-     * @returns {ProcessUpdate}
+     * @returns {DriverVersion}
      */
     static createFrom($$source = {}) {
         let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
-        return new ProcessUpdate(/** @type {Partial<ProcessUpdate>} */($$parsedSource));
+        return new DriverVersion(/** @type {Partial<DriverVersion>} */($$parsedSource));
+    }
+}
+
+/**
+ * Flow is an ordered list of steps that is run against a tab. Flows are
+ * loaded from .json, .yaml or .yml files in the flows directory and are
+ * identified by their file name (without the extension).
+ */
+export class Flow {
+    /**
+     * Creates a new Flow instance.
+     * @param {Partial<Flow>} [$$source = {}] - The source object to create the Flow.
+     */
+    constructor($$source = {}) {
+        if (!("name" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["name"] = "";
+        }
+        if (!("description" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["description"] = "";
+        }
+        if (!("steps" in $$source)) {
+            /**
+             * @member
+             * @type {FlowStep[]}
+             */
+            this["steps"] = [];
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * Error is set by ListFlows if the file could not be loaded, the flow
+             * has no steps then.
+             * @member
+             * @type {string | undefined}
+             */
+            this["error"] = undefined;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new Flow instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {Flow}
+     */
+    static createFrom($$source = {}) {
+        const $$createField2_0 = $$createType6;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("steps" in $$parsedSource) {
+            $$parsedSource["steps"] = $$createField2_0($$parsedSource["steps"]);
+        }
+        return new Flow(/** @type {Partial<Flow>} */($$parsedSource));
+    }
+}
+
+/**
+ * FlowRun is the state of a flow being run (or that has been run) against a
+ * tab.
+ */
+export class FlowRun {
+    /**
+     * Creates a new FlowRun instance.
+     * @param {Partial<FlowRun>} [$$source = {}] - The source object to create the FlowRun.
+     */
+    constructor($$source = {}) {
+        if (!("runID" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["runID"] = "";
+        }
+        if (!("processID" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["processID"] = "";
+        }
+        if (!("flowName" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["flowName"] = "";
+        }
+        if (!("tabID" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["tabID"] = 0;
+        }
+        if (!("status" in $$source)) {
+            /**
+             * running|succeeded|failed|cancelled
+             * @member
+             * @type {string}
+             */
+            this["status"] = "";
+        }
+        if (!("steps" in $$source)) {
+            /**
+             * @member
+             * @type {FlowStepResult[]}
+             */
+            this["steps"] = [];
+        }
+        if (!("outputs" in $$source)) {
+            /**
+             * @member
+             * @type {{ [_ in string]?: string }}
+             */
+            this["outputs"] = {};
+        }
+        if (!("error" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["error"] = "";
+        }
+        if (!("startedAt" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["startedAt"] = 0;
+        }
+        if (!("finishedAt" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["finishedAt"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new FlowRun instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {FlowRun}
+     */
+    static createFrom($$source = {}) {
+        const $$createField5_0 = $$createType8;
+        const $$createField6_0 = $$createType9;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("steps" in $$parsedSource) {
+            $$parsedSource["steps"] = $$createField5_0($$parsedSource["steps"]);
+        }
+        if ("outputs" in $$parsedSource) {
+            $$parsedSource["outputs"] = $$createField6_0($$parsedSource["outputs"]);
+        }
+        return new FlowRun(/** @type {Partial<FlowRun>} */($$parsedSource));
+    }
+}
+
+/**
+ * FlowStep is a single action in a flow.
+ * 
+ * 	goto:       URL
+ * 	click:      Selector
+ * 	fill:       Selector, Value
+ * 	select:     Selector, Value (the option value or label)
+ * 	wait-for:   Selector or URL
+ * 	press:      Value (the key, e.g. "Enter"), optionally Selector
+ * 	extract:    Selector, Name, optionally Attribute (defaults to the text content)
+ * 	screenshot: optionally Selector and Name
+ */
+export class FlowStep {
+    /**
+     * Creates a new FlowStep instance.
+     * @param {Partial<FlowStep>} [$$source = {}] - The source object to create the FlowStep.
+     */
+    constructor($$source = {}) {
+        if (!("action" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["action"] = "";
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {string | undefined}
+             */
+            this["selector"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {string | undefined}
+             */
+            this["url"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {string | undefined}
+             */
+            this["value"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {string | undefined}
+             */
+            this["attribute"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {string | undefined}
+             */
+            this["name"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * Timeout is in milliseconds. If zero, defaultFlowStepTimeout is used.
+             * @member
+             * @type {number | undefined}
+             */
+            this["timeout"] = undefined;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new FlowStep instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {FlowStep}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new FlowStep(/** @type {Partial<FlowStep>} */($$parsedSource));
+    }
+}
+
+/**
+ * FlowStepResult is the state of a single step of a FlowRun.
+ */
+export class FlowStepResult {
+    /**
+     * Creates a new FlowStepResult instance.
+     * @param {Partial<FlowStepResult>} [$$source = {}] - The source object to create the FlowStepResult.
+     */
+    constructor($$source = {}) {
+        if (!("step" in $$source)) {
+            /**
+             * @member
+             * @type {FlowStep}
+             */
+            this["step"] = (new FlowStep());
+        }
+        if (!("status" in $$source)) {
+            /**
+             * pending|running|succeeded|failed|cancelled|skipped
+             * @member
+             * @type {string}
+             */
+            this["status"] = "";
+        }
+        if (!("message" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["message"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new FlowStepResult instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {FlowStepResult}
+     */
+    static createFrom($$source = {}) {
+        const $$createField0_0 = $$createType5;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("step" in $$parsedSource) {
+            $$parsedSource["step"] = $$createField0_0($$parsedSource["step"]);
+        }
+        return new FlowStepResult(/** @type {Partial<FlowStepResult>} */($$parsedSource));
+    }
+}
+
+/**
+ * InstallDriverEvent is one step of a driver install. Category is one of:
+ * 
+ *   - "started": the install has begun, Message is the process ID.
+ *   - "info": a log line.
+ *   - "progress": Bytes of Total bytes of the driver archive have been
+ *     downloaded (Total is -1 if unknown).
+ *   - "file": FileName (Bytes bytes) is being unzipped.
+ *   - "browser": a line of output from the browser install.
+ *   - "done": the install succeeded.
+ *   - "error": the install failed or was cancelled.
+ *   - "checksum-mismatch": the downloaded driver archive does not match its
+ *     checksum. It has been deleted, the next install downloads it again.
+ *   - "checksum-unknown": no checksum is known for the driver archive, it
+ *     can be installed by starting the install again with allowUnverified.
+ * 
+ * "done", "error", "checksum-mismatch" and "checksum-unknown" are always the
+ * last event of an install.
+ */
+export class InstallDriverEvent {
+    /**
+     * Creates a new InstallDriverEvent instance.
+     * @param {Partial<InstallDriverEvent>} [$$source = {}] - The source object to create the InstallDriverEvent.
+     */
+    constructor($$source = {}) {
+        if (!("eventID" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["eventID"] = "";
+        }
+        if (!("category" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["category"] = "";
+        }
+        if (!("message" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["message"] = "";
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {number | undefined}
+             */
+            this["bytes"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {number | undefined}
+             */
+            this["total"] = undefined;
+        }
+        if (/** @type {any} */(false)) {
+            /**
+             * @member
+             * @type {string | undefined}
+             */
+            this["fileName"] = undefined;
+        }
+        if (!("timestamp" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["timestamp"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new InstallDriverEvent instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {InstallDriverEvent}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new InstallDriverEvent(/** @type {Partial<InstallDriverEvent>} */($$parsedSource));
+    }
+}
+
+export class MessageDialogOptions {
+    /**
+     * Creates a new MessageDialogOptions instance.
+     * @param {Partial<MessageDialogOptions>} [$$source = {}] - The source object to create the MessageDialogOptions.
+     */
+    constructor($$source = {}) {
+        if (!("DialogType" in $$source)) {
+            /**
+             * Info|Question|Warning|Error
+             * @member
+             * @type {string}
+             */
+            this["DialogType"] = "";
+        }
+        if (!("Title" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["Title"] = "";
+        }
+        if (!("Message" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["Message"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new MessageDialogOptions instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {MessageDialogOptions}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new MessageDialogOptions(/** @type {Partial<MessageDialogOptions>} */($$parsedSource));
+    }
+}
+
+/**
+ * NetworkSettings configure how the backend reaches the internet. They apply
+ * to every outbound HTTP client the backend creates and to the driver when it
+ * downloads browsers. They are part of Settings.
+ */
+export class NetworkSettings {
+    /**
+     * Creates a new NetworkSettings instance.
+     * @param {Partial<NetworkSettings>} [$$source = {}] - The source object to create the NetworkSettings.
+     */
+    constructor($$source = {}) {
+        if (!("proxyURL" in $$source)) {
+            /**
+             * ProxyURL is the proxy for both HTTP and HTTPS requests, e.g.
+             * "http://proxy.example.com:3128". If empty, HTTPS_PROXY, HTTP_PROXY and
+             * NO_PROXY from the environment are used.
+             * @member
+             * @type {string}
+             */
+            this["proxyURL"] = "";
+        }
+        if (!("proxyUsername" in $$source)) {
+            /**
+             * ProxyUsername and ProxyPassword are sent to the proxy with basic
+             * authentication. They are stored in plain text, but the password is
+             * never sent to the windows or API clients, see GetSettings.
+             * @member
+             * @type {string}
+             */
+            this["proxyUsername"] = "";
+        }
+        if (!("proxyPassword" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["proxyPassword"] = "";
+        }
+        if (!("noProxy" in $$source)) {
+            /**
+             * NoProxy lists the hosts that bypass ProxyURL, in the same format as
+             * NO_PROXY (comma separated host names, domains and CIDR ranges).
+             * @member
+             * @type {string}
+             */
+            this["noProxy"] = "";
+        }
+        if (!("caBundleFile" in $$source)) {
+            /**
+             * CABundleFile is a PEM file of certificate authorities to trust in
+             * addition to the system ones, e.g. for a TLS-intercepting proxy.
+             * @member
+             * @type {string}
+             */
+            this["caBundleFile"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new NetworkSettings instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {NetworkSettings}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new NetworkSettings(/** @type {Partial<NetworkSettings>} */($$parsedSource));
+    }
+}
+
+export class OpenBrowserOptions {
+    /**
+     * Creates a new OpenBrowserOptions instance.
+     * @param {Partial<OpenBrowserOptions>} [$$source = {}] - The source object to create the OpenBrowserOptions.
+     */
+    constructor($$source = {}) {
+        if (!("ProfileName" in $$source)) {
+            /**
+             * ProfileName is the profile to open the browser with.
+             * Default: "default"
+             * @member
+             * @type {string}
+             */
+            this["ProfileName"] = "";
+        }
+        if (!("LaunchMode" in $$source)) {
+            /**
+             * LaunchMode is how the browser is acquired.
+             * Default: LaunchModeCDP
+             * LaunchModeCDP|LaunchModePersistent
+             * @member
+             * @type {string}
+             */
+            this["LaunchMode"] = "";
+        }
+        if (!("BrowserName" in $$source)) {
+            /**
+             * BrowserName is the browser engine to use. Only BrowserChromium can be
+             * used with LaunchModeCDP.
+             * Default: BrowserChromium
+             * BrowserChromium|BrowserFirefox|BrowserWebKit
+             * @member
+             * @type {string}
+             */
+            this["BrowserName"] = "";
+        }
+        if (!("Headless" in $$source)) {
+            /**
+             * Headless hides the browser window. Only applies to LaunchModePersistent.
+             * @member
+             * @type {boolean}
+             */
+            this["Headless"] = false;
+        }
+        if (!("AutoReconnect" in $$source)) {
+            /**
+             * AutoReconnect reopens the browser with backoff if it is closed or the
+             * connection to it is lost.
+             * @member
+             * @type {boolean}
+             */
+            this["AutoReconnect"] = false;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new OpenBrowserOptions instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {OpenBrowserOptions}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new OpenBrowserOptions(/** @type {Partial<OpenBrowserOptions>} */($$parsedSource));
+    }
+}
+
+export class ProcessUpdate {
+    /**
+     * Creates a new ProcessUpdate instance.
+     * @param {Partial<ProcessUpdate>} [$$source = {}] - The source object to create the ProcessUpdate.
+     */
+    constructor($$source = {}) {
+        if (!("processID" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["processID"] = "";
+        }
+        if (!("message" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["message"] = "";
+        }
+        if (!("progressValue" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["progressValue"] = 0;
+        }
+        if (!("progressMax" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["progressMax"] = 0;
+        }
+        if (!("timestamp" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["timestamp"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new ProcessUpdate instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {ProcessUpdate}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new ProcessUpdate(/** @type {Partial<ProcessUpdate>} */($$parsedSource));
+    }
+}
+
+/**
+ * Profile is a Chrome user data directory managed by the backend. Each
+ * profile has its own logged-in identities and its own remote debugging port
+ * so that several browsers can be attached at the same time.
+ */
+export class Profile {
+    /**
+     * Creates a new Profile instance.
+     * @param {Partial<Profile>} [$$source = {}] - The source object to create the Profile.
+     */
+    constructor($$source = {}) {
+        if (!("name" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["name"] = "";
+        }
+        if (!("directory" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["directory"] = "";
+        }
+        if (!("cdpPort" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["cdpPort"] = 0;
+        }
+        if (!("connected" in $$source)) {
+            /**
+             * @member
+             * @type {boolean}
+             */
+            this["connected"] = false;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new Profile instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {Profile}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new Profile(/** @type {Partial<Profile>} */($$parsedSource));
+    }
+}
+
+/**
+ * Settings are the application settings. They are stored in settings.json in
+ * the OS config directory (see settingsFile) and can be overridden by
+ * environment variables and command line flags, in that order.
+ * 
+ * Empty fields take their default: the directories default to subdirectories
+ * of DataDirectory, which itself defaults to ~/browserautomate.
+ */
+export class Settings {
+    /**
+     * Creates a new Settings instance.
+     * @param {Partial<Settings>} [$$source = {}] - The source object to create the Settings.
+     */
+    constructor($$source = {}) {
+        if (!("dataDirectory" in $$source)) {
+            /**
+             * DataDirectory holds the profiles, flows, driver downloads and the
+             * other files of the app.
+             * @member
+             * @type {string}
+             */
+            this["dataDirectory"] = "";
+        }
+        if (!("driversDirectory" in $$source)) {
+            /**
+             * DriversDirectory holds one subdirectory per Playwright driver version.
+             * PLAYWRIGHT_DRIVER_PATH is not an override for it: as in playwright-go
+             * it names the driver directory itself, see initialize.
+             * @member
+             * @type {string}
+             */
+            this["driversDirectory"] = "";
+        }
+        if (!("browsersDirectory" in $$source)) {
+            /**
+             * BrowsersDirectory holds the browser builds installed by Playwright.
+             * @member
+             * @type {string}
+             */
+            this["browsersDirectory"] = "";
+        }
+        if (!("profilesDirectory" in $$source)) {
+            /**
+             * ProfilesDirectory holds one Chrome user data directory per profile.
+             * @member
+             * @type {string}
+             */
+            this["profilesDirectory"] = "";
+        }
+        if (!("httpAddress" in $$source)) {
+            /**
+             * HTTPAddress is the address of the local HTTP server.
+             * @member
+             * @type {string}
+             */
+            this["httpAddress"] = "";
+        }
+        if (!("defaultCDPPort" in $$source)) {
+            /**
+             * DefaultCDPPort is the remote debugging port for profiles that do not
+             * configure their own. Zero picks a free port.
+             * @member
+             * @type {number}
+             */
+            this["defaultCDPPort"] = 0;
+        }
+        if (!("downloadMirrors" in $$source)) {
+            /**
+             * DownloadMirrors are the hosts the driver is downloaded from, tried in
+             * order.
+             * @member
+             * @type {string[]}
+             */
+            this["downloadMirrors"] = [];
+        }
+        if (!("proxyURL" in $$source)) {
+            /**
+             * ProxyURL is the proxy for both HTTP and HTTPS requests, e.g.
+             * "http://proxy.example.com:3128". If empty, HTTPS_PROXY, HTTP_PROXY and
+             * NO_PROXY from the environment are used.
+             * @member
+             * @type {string}
+             */
+            this["proxyURL"] = "";
+        }
+        if (!("proxyUsername" in $$source)) {
+            /**
+             * ProxyUsername and ProxyPassword are sent to the proxy with basic
+             * authentication. They are stored in plain text, but the password is
+             * never sent to the windows or API clients, see GetSettings.
+             * @member
+             * @type {string}
+             */
+            this["proxyUsername"] = "";
+        }
+        if (!("proxyPassword" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["proxyPassword"] = "";
+        }
+        if (!("noProxy" in $$source)) {
+            /**
+             * NoProxy lists the hosts that bypass ProxyURL, in the same format as
+             * NO_PROXY (comma separated host names, domains and CIDR ranges).
+             * @member
+             * @type {string}
+             */
+            this["noProxy"] = "";
+        }
+        if (!("caBundleFile" in $$source)) {
+            /**
+             * CABundleFile is a PEM file of certificate authorities to trust in
+             * addition to the system ones, e.g. for a TLS-intercepting proxy.
+             * @member
+             * @type {string}
+             */
+            this["caBundleFile"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new Settings instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {Settings}
+     */
+    static createFrom($$source = {}) {
+        const $$createField6_0 = $$createType0;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("downloadMirrors" in $$parsedSource) {
+            $$parsedSource["downloadMirrors"] = $$createField6_0($$parsedSource["downloadMirrors"]);
+        }
+        return new Settings(/** @type {Partial<Settings>} */($$parsedSource));
+    }
+}
+
+/**
+ * SettingsInfo is what the settings window shows: the saved settings, the
+ * settings in effect after applying defaults and overrides, and where each
+ * override comes from.
+ */
+export class SettingsInfo {
+    /**
+     * Creates a new SettingsInfo instance.
+     * @param {Partial<SettingsInfo>} [$$source = {}] - The source object to create the SettingsInfo.
+     */
+    constructor($$source = {}) {
+        if (!("saved" in $$source)) {
+            /**
+             * @member
+             * @type {Settings}
+             */
+            this["saved"] = (new Settings());
+        }
+        if (!("effective" in $$source)) {
+            /**
+             * @member
+             * @type {Settings}
+             */
+            this["effective"] = (new Settings());
+        }
+        if (!("overrides" in $$source)) {
+            /**
+             * Overrides maps the JSON name of each overridden setting to the
+             * environment variable or flag that overrides it.
+             * @member
+             * @type {{ [_ in string]?: string }}
+             */
+            this["overrides"] = {};
+        }
+        if (!("restartRequired" in $$source)) {
+            /**
+             * RestartRequired is set if a saved directory or the HTTP address
+             * differs from the one in use, these only take effect after a restart.
+             * @member
+             * @type {boolean}
+             */
+            this["restartRequired"] = false;
+        }
+        if (!("proxyPasswordSet" in $$source)) {
+            /**
+             * ProxyPasswordSet is set if a proxy password is saved. The password
+             * itself is never sent out, Saved and Effective have it blanked.
+             * @member
+             * @type {boolean}
+             */
+            this["proxyPasswordSet"] = false;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new SettingsInfo instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {SettingsInfo}
+     */
+    static createFrom($$source = {}) {
+        const $$createField0_0 = $$createType10;
+        const $$createField1_0 = $$createType10;
+        const $$createField2_0 = $$createType9;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("saved" in $$parsedSource) {
+            $$parsedSource["saved"] = $$createField0_0($$parsedSource["saved"]);
+        }
+        if ("effective" in $$parsedSource) {
+            $$parsedSource["effective"] = $$createField1_0($$parsedSource["effective"]);
+        }
+        if ("overrides" in $$parsedSource) {
+            $$parsedSource["overrides"] = $$createField2_0($$parsedSource["overrides"]);
+        }
+        return new SettingsInfo(/** @type {Partial<SettingsInfo>} */($$parsedSource));
+    }
+}
+
+/**
+ * StartupStatus is the state of the backend's initialisation.
+ */
+export class StartupStatus {
+    /**
+     * Creates a new StartupStatus instance.
+     * @param {Partial<StartupStatus>} [$$source = {}] - The source object to create the StartupStatus.
+     */
+    constructor($$source = {}) {
+        if (!("state" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["state"] = "";
+        }
+        if (!("error" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["error"] = "";
+        }
+        if (!("driversDirectory" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["driversDirectory"] = "";
+        }
+        if (!("settingsInvalid" in $$source)) {
+            /**
+             * SettingsInvalid is set if startup failed because the saved settings
+             * are invalid, ResetSettings fixes that.
+             * @member
+             * @type {boolean}
+             */
+            this["settingsInvalid"] = false;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new StartupStatus instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {StartupStatus}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new StartupStatus(/** @type {Partial<StartupStatus>} */($$parsedSource));
+    }
+}
+
+/**
+ * Tab is a page open in one of the profiles' browsers.
+ */
+export class Tab {
+    /**
+     * Creates a new Tab instance.
+     * @param {Partial<Tab>} [$$source = {}] - The source object to create the Tab.
+     */
+    constructor($$source = {}) {
+        if (!("tabID" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["tabID"] = 0;
+        }
+        if (!("profileName" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["profileName"] = "";
+        }
+        if (!("url" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["url"] = "";
+        }
+        if (!("title" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["title"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new Tab instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {Tab}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new Tab(/** @type {Partial<Tab>} */($$parsedSource));
+    }
+}
+
+/**
+ * TabDetail is the live state of a tab, emitted to all windows as
+ * TabDetailUpdated whenever it changes.
+ */
+export class TabDetail {
+    /**
+     * Creates a new TabDetail instance.
+     * @param {Partial<TabDetail>} [$$source = {}] - The source object to create the TabDetail.
+     */
+    constructor($$source = {}) {
+        if (!("tabID" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["tabID"] = 0;
+        }
+        if (!("profileName" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["profileName"] = "";
+        }
+        if (!("url" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["url"] = "";
+        }
+        if (!("title" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["title"] = "";
+        }
+        if (!("faviconURL" in $$source)) {
+            /**
+             * data URL
+             * @member
+             * @type {string}
+             */
+            this["faviconURL"] = "";
+        }
+        if (!("loadState" in $$source)) {
+            /**
+             * loading|domcontentloaded|load
+             * @member
+             * @type {string}
+             */
+            this["loadState"] = "";
+        }
+        if (!("consoleMessageCount" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["consoleMessageCount"] = 0;
+        }
+        if (!("lastError" in $$source)) {
+            /**
+             * @member
+             * @type {string}
+             */
+            this["lastError"] = "";
+        }
+        if (!("timestamp" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["timestamp"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new TabDetail instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {TabDetail}
+     */
+    static createFrom($$source = {}) {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new TabDetail(/** @type {Partial<TabDetail>} */($$parsedSource));
+    }
+}
+
+/**
+ * TabEvent is emitted to all windows as TabOpened, TabClosed or TabNavigated.
+ */
+export class TabEvent {
+    /**
+     * Creates a new TabEvent instance.
+     * @param {Partial<TabEvent>} [$$source = {}] - The source object to create the TabEvent.
+     */
+    constructor($$source = {}) {
+        if (!("tab" in $$source)) {
+            /**
+             * @member
+             * @type {Tab}
+             */
+            this["tab"] = (new Tab());
+        }
+        if (!("timestamp" in $$source)) {
+            /**
+             * @member
+             * @type {number}
+             */
+            this["timestamp"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new TabEvent instance from a string or object.
+     * @param {any} [$$source = {}]
+     * @returns {TabEvent}
+     */
+    static createFrom($$source = {}) {
+        const $$createField0_0 = $$createType11;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("tab" in $$parsedSource) {
+            $$parsedSource["tab"] = $$createField0_0($$parsedSource["tab"]);
+        }
+        return new TabEvent(/** @type {Partial<TabEvent>} */($$parsedSource));
     }
 }
 
@@ -505,9 +1900,9 @@ export class WebviewWindowOptions {
      * @returns {WebviewWindowOptions}
      */
     static createFrom($$source = {}) {
-        const $$createField26_0 = $$createType0;
-        const $$createField27_0 = $$createType1;
-        const $$createField28_0 = $$createType2;
+        const $$createField26_0 = $$createType12;
+        const $$createField27_0 = $$createType13;
+        const $$createField28_0 = $$createType14;
         let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
         if ("Mac" in $$parsedSource) {
             $$parsedSource["Mac"] = $$createField26_0($$parsedSource["Mac"]);
@@ -523,6 +1918,18 @@ export class WebviewWindowOptions {
 }
 
 // Private type creation functions
-const $$createType0 = application$0.MacWindow.createFrom;
-const $$createType1 = application$0.WindowsWindow.createFrom;
-const $$createType2 = application$0.LinuxWindow.createFrom;
+const $$createType0 = $Create.Array($Create.Any);
+const $$createType1 = DriverVersion.createFrom;
+const $$createType2 = $Create.Array($$createType1);
+const $$createType3 = DriverArchive.createFrom;
+const $$createType4 = $Create.Array($$createType3);
+const $$createType5 = FlowStep.createFrom;
+const $$createType6 = $Create.Array($$createType5);
+const $$createType7 = FlowStepResult.createFrom;
+const $$createType8 = $Create.Array($$createType7);
+const $$createType9 = $Create.Map($Create.Any, $Create.Any);
+const $$createType10 = Settings.createFrom;
+const $$createType11 = Tab.createFrom;
+const $$createType12 = application$0.MacWindow.createFrom;
+const $$createType13 = application$0.WindowsWindow.createFrom;
+const $$createType14 = application$0.LinuxWindow.createFrom;
@@ -12,13 +12,25 @@ import * as main$0 from "../../../../../changeme/models.js";
 
 function configure() {
     Object.freeze(Object.assign($Create.Events, {
-        "InstallDriverEvent": $$createType0,
-        "ProcessUpdate": $$createType1,
+        "BrowserStatus": $$createType0,
+        "InstallDriverEvent": $$createType1,
+        "ProcessUpdate": $$createType2,
+        "SettingsChanged": $$createType3,
+        "StartupStatus": $$createType4,
+        "TabClosed": $$createType5,
+        "TabDetailUpdated": $$createType6,
+        "TabNavigated": $$createType5,
+        "TabOpened": $$createType5,
     }));
 }
 
 // Private type creation functions
-const $$createType0 = main$0.InstallDriverEvent.createFrom;
-const $$createType1 = main$0.ProcessUpdate.createFrom;
+const $$createType0 = main$0.BrowserStatus.createFrom;
+const $$createType1 = main$0.InstallDriverEvent.createFrom;
+const $$createType2 = main$0.ProcessUpdate.createFrom;
+const $$createType3 = main$0.SettingsInfo.createFrom;
+const $$createType4 = main$0.StartupStatus.createFrom;
+const $$createType5 = main$0.TabEvent.createFrom;
+const $$createType6 = main$0.TabDetail.createFrom;
 
 configure();
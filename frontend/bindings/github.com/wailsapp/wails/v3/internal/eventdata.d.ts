@@ -12,8 +12,15 @@ import type * as main$0 from "../../../../../changeme/models.js";
 declare module "@wailsio/runtime" {
     namespace Events {
         interface CustomEvents {
+            "BrowserStatus": main$0.BrowserStatus;
             "InstallDriverEvent": main$0.InstallDriverEvent;
             "ProcessUpdate": main$0.ProcessUpdate;
+            "SettingsChanged": main$0.SettingsInfo;
+            "StartupStatus": main$0.StartupStatus;
+            "TabClosed": main$0.TabEvent;
+            "TabDetailUpdated": main$0.TabDetail;
+            "TabNavigated": main$0.TabEvent;
+            "TabOpened": main$0.TabEvent;
         }
     }
 }
@@ -1,12 +1,65 @@
 import { Events, Window } from "@wailsio/runtime";
-import { Backend, ProcessUpdate, InstallDriverEvent, WebviewWindowOptions, MessageDialogOptions } from "./bindings/changeme";
+import { Backend, ProcessUpdate, InstallDriverEvent, WebviewWindowOptions, MessageDialogOptions, OpenBrowserOptions, BrowserStatus, Tab, TabEvent, TabDetail } from "./bindings/changeme";
 import "basecoat-css/basecoat";
 import "basecoat-css/all";
 
+/**
+ * Shows the health checks in the health dialog if any of them is not ok and
+ * waits until the user continues. "recheck" fetches the report again.
+ * @param {{name: string, status: string, message: string, remediation?: string}[]} checks
+ */
+async function showHealthChecks(checks) {
+  const healthDialog = document.getElementById("healthDialog");
+  if (!(healthDialog instanceof HTMLDialogElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const healthChecks = document.getElementById("healthChecks");
+  if (!(healthChecks instanceof HTMLElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const recheckButton = document.getElementById("recheckButton");
+  if (!(recheckButton instanceof HTMLButtonElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const render = function() {
+    healthChecks.replaceChildren();
+    for (const check of checks) {
+      const item = document.createElement("li");
+      item.className = check.status == "error" ? "text-red-700" : check.status == "warning" ? "text-amber-700" : "";
+      item.textContent = `${check.status}: ${check.name}: ${check.message}`;
+      if (check.remediation) {
+        const remediation = document.createElement("div");
+        remediation.className = "text-sm text-gray-600";
+        remediation.textContent = check.remediation;
+        item.append(remediation);
+      }
+      healthChecks.append(item);
+    }
+  };
+  if (checks.every(function(check) { return check.status == "ok"; })) {
+    return;
+  }
+  render();
+  const recheck = async function() {
+    const response = await fetch("/backend/driver/");
+    checks = (await response.json()).checks;
+    render();
+  };
+  recheckButton.addEventListener("click", recheck);
+  try {
+    await new Promise(function(resolve) {
+      healthDialog.addEventListener("close", resolve, { once: true });
+      healthDialog.showModal();
+    });
+  } finally {
+    recheckButton.removeEventListener("click", recheck);
+  }
+}
+
 (async function init() {
   try {
     let response = await fetch("/backend/driver/");
-    const driverData = await response.json();
+    let driverData = await response.json();
     if (!driverData.currentVersion.includes(driverData.requiredVersion)) {
       const params = new URLSearchParams();
       params.append("currentVersion", driverData.currentVersion);
@@ -27,9 +80,12 @@ import "basecoat-css/all";
       });
       await Backend.EnableWindow("main", true);
       await Backend.FocusWindow("main");
+      response = await fetch("/backend/driver/");
+      driverData = await response.json();
     }
+    await showHealthChecks(driverData.checks);
     await Backend.StartPlaywright();
-    await Backend.OpenBrowser();
+    await Backend.OpenBrowser(new OpenBrowserOptions({ ProfileName: "default" }));
     console.log(await Backend.Hello());
   } catch (err) {
     console.error(err);
@@ -93,13 +149,17 @@ try {
         return;
       }
       this.processStack[processInfo.index] = processUpdate;
+      if (processInfo.index == this.processStack.length - 1) {
+        this.currentProcess = processUpdate;
+      }
       document.dispatchEvent(new Event("CurrentProcessUpdated", { bubbles: true }));
       return;
     },
   }
   Events.On("ProcessUpdate", async function(event) {
     const windowName = await Window.Name();
-    if (event.sender != windowName) {
+    // Processes not started from a window are broadcast without a sender.
+    if (event.sender && event.sender != windowName) {
       return;
     }
     statusBarState.pushProcessUpdate(event.data);
@@ -111,23 +171,315 @@ try {
   }
   initEvents.add("CurrentProcessUpdated");
   document.addEventListener("CurrentProcessUpdated", function() {
+    statusBarSpinner.style.visibility = statusBarState.currentProcess == null ? "hidden" : "";
   });
-  // TODO: register an init function which is called at the bottom which hides or shows the statusBarSpinner.
 
   const statusBarStatus = statusBar.querySelector("[role=status]");
   if (!(statusBarStatus instanceof HTMLElement)) {
     throw new Error("element not found or invalid");
   }
-  // TODO: register an init function which is called at the bottom which hides or shows the statusBarStatus message.
   document.addEventListener("CurrentProcessUpdated", function() {
-    // TODO: consult statusBar state and update textContent accordingly.
+    const currentProcess = statusBarState.currentProcess;
+    if (currentProcess == null) {
+      statusBarStatus.textContent = "";
+      return;
+    }
+    let message = currentProcess.message;
+    if (currentProcess.progressMax > 1) {
+      message += ` (${Math.floor(currentProcess.progressValue * 100 / currentProcess.progressMax)}%)`;
+    }
+    if (statusBarState.processStack.length > 1) {
+      message += ` [+${statusBarState.processStack.length - 1} more]`;
+    }
+    statusBarStatus.textContent = message;
+  });
+
+  const cancelProcessButton = document.getElementById("cancelProcessButton");
+  if (!(cancelProcessButton instanceof HTMLButtonElement)) {
+    throw new Error("element not found or invalid");
+  }
+  document.addEventListener("CurrentProcessUpdated", function() {
+    cancelProcessButton.style.visibility = statusBarState.currentProcess == null ? "hidden" : "";
+  });
+  document.addEventListener("CancelProcess", async function() {
+    const currentProcess = statusBarState.currentProcess;
+    if (currentProcess == null) {
+      return;
+    }
+    try {
+      await Backend.CancelProcess(currentProcess.processID);
+    } catch (err) {
+      // The process finished in the meantime.
+      console.error(err);
+    }
+  });
+
+  const browserSelect = document.getElementById("browserSelect");
+  if (!(browserSelect instanceof HTMLSelectElement)) {
+    throw new Error("element not found or invalid");
+  }
+  initEvents.add("BrowserLaunchersChanged");
+  document.addEventListener("BrowserLaunchersChanged", async function() {
+    const [browserLaunchers, selected] = await Promise.all([
+      Backend.ListBrowserLaunchers(),
+      Backend.SelectedBrowserLauncher().catch(() => null),
+    ]);
+    for (const option of Array.from(browserSelect.options)) {
+      if (!option.disabled) {
+        option.remove();
+      }
+    }
+    for (const browserLauncher of browserLaunchers) {
+      const option = document.createElement("option");
+      option.value = browserLauncher.id;
+      option.textContent = browserLauncher.name;
+      option.title = browserLauncher.executablePath;
+      option.selected = selected != null && selected.id == browserLauncher.id;
+      browserSelect.append(option);
+    }
+  });
+  browserSelect.addEventListener("change", async function() {
+    try {
+      await Backend.SelectBrowserLauncher(browserSelect.value);
+    } catch (err) {
+      await Backend.Dialog(new MessageDialogOptions({
+        Title: "Error",
+        Message: err instanceof Error ? err.message : String(err),
+      }));
+    }
+  });
+
+  const profileSelect = document.getElementById("profileSelect");
+  if (!(profileSelect instanceof HTMLSelectElement)) {
+    throw new Error("element not found or invalid");
+  }
+  /**
+   * The latest BrowserStatus of each profile that is reconnecting or gave up.
+   * @type {Map<string, BrowserStatus>}
+   */
+  const browserStatuses = new Map();
+  initEvents.add("ProfilesChanged");
+  document.addEventListener("ProfilesChanged", async function() {
+    const profiles = await Backend.ListProfiles();
+    const selectedProfileName = profileSelect.value || "default";
+    for (const option of Array.from(profileSelect.options)) {
+      if (!option.disabled) {
+        option.remove();
+      }
+    }
+    for (const profile of profiles) {
+      const option = document.createElement("option");
+      option.value = profile.name;
+      const browserStatus = browserStatuses.get(profile.name);
+      if (profile.connected) {
+        option.textContent = `${profile.name} (connected)`;
+      } else if (browserStatus != null && browserStatus.status == "reconnecting") {
+        option.textContent = `${profile.name} (reconnecting, attempt ${browserStatus.attempt})`;
+      } else if (browserStatus != null && browserStatus.status == "failed") {
+        option.textContent = `${profile.name} (reconnect failed)`;
+      } else {
+        option.textContent = profile.name;
+      }
+      option.title = browserStatus == null ? "" : browserStatus.message;
+      option.selected = profile.name == selectedProfileName;
+      profileSelect.append(option);
+    }
+  });
+
+  Events.On("BrowserStatus", function(event) {
+    const browserStatus = new BrowserStatus(event.data);
+    if (browserStatus.status == "reconnecting" || browserStatus.status == "failed") {
+      browserStatuses.set(browserStatus.profileName, browserStatus);
+    } else {
+      browserStatuses.delete(browserStatus.profileName);
+    }
+    document.dispatchEvent(new Event("ProfilesChanged", { bubbles: true }));
+  });
+
+  const tabSelect = document.getElementById("tabSelect");
+  if (!(tabSelect instanceof HTMLSelectElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const tabFavicon = document.getElementById("tabFavicon");
+  if (!(tabFavicon instanceof HTMLImageElement)) {
+    throw new Error("element not found or invalid");
+  }
+  /** @type {Map<number, TabDetail>} */
+  const tabDetails = new Map();
+  /**
+   * @param {Tab | TabDetail} tab
+   * @returns {string}
+   */
+  const tabLabel = function(tab) {
+    let label = `[${tab.profileName}] ${tab.title || tab.url || "about:blank"}`;
+    const tabDetail = tabDetails.get(tab.tabID);
+    if (tabDetail == null) {
+      return label;
+    }
+    if (tabDetail.loadState != "load") {
+      label = `(${tabDetail.loadState}) ${label}`;
+    }
+    if (tabDetail.consoleMessageCount > 0) {
+      label += ` [${tabDetail.consoleMessageCount} console]`;
+    }
+    if (tabDetail.lastError != "") {
+      label += " [error]";
+    }
+    return label;
+  };
+  /**
+   * @param {Tab | TabDetail} tab
+   * @returns {string}
+   */
+  const tabTooltip = function(tab) {
+    const tabDetail = tabDetails.get(tab.tabID);
+    if (tabDetail == null || tabDetail.lastError == "") {
+      return tab.url;
+    }
+    return `${tab.url}\n${tabDetail.lastError}`;
+  };
+  document.addEventListener("TabSelected", function() {
+    const tabDetail = tabDetails.get(Number(tabSelect.value));
+    if (tabDetail == null || tabDetail.faviconURL == "") {
+      tabFavicon.style.visibility = "hidden";
+      tabFavicon.removeAttribute("src");
+      return;
+    }
+    tabFavicon.src = tabDetail.faviconURL;
+    tabFavicon.style.visibility = "";
+  });
+  Events.On("TabDetailUpdated", function(event) {
+    const tabDetail = new TabDetail(event.data);
+    tabDetails.set(tabDetail.tabID, tabDetail);
+    const option = tabSelect.querySelector(`option[value="${tabDetail.tabID}"]`);
+    if (option instanceof HTMLOptionElement) {
+      option.textContent = tabLabel(tabDetail);
+      option.title = tabTooltip(tabDetail);
+    }
+    if (tabSelect.value == String(tabDetail.tabID)) {
+      document.dispatchEvent(new Event("TabSelected", { bubbles: true }));
+    }
+  });
+  initEvents.add("TabsChanged");
+  document.addEventListener("TabsChanged", async function() {
+    const tabs = await Backend.ListTabDetails();
+    tabDetails.clear();
+    for (const tabDetail of tabs) {
+      tabDetails.set(tabDetail.tabID, tabDetail);
+    }
+    const selectedTabID = tabSelect.value;
+    for (const option of Array.from(tabSelect.options)) {
+      if (!option.disabled) {
+        option.remove();
+      }
+    }
+    for (const tab of tabs) {
+      const option = document.createElement("option");
+      option.value = String(tab.tabID);
+      option.textContent = tabLabel(tab);
+      option.title = tabTooltip(tab);
+      option.selected = option.value == selectedTabID;
+      tabSelect.append(option);
+    }
+    document.dispatchEvent(new Event("TabSelected", { bubbles: true }));
+  });
+  Events.On("TabOpened", function(event) {
+    const tab = new TabEvent(event.data).tab;
+    const option = document.createElement("option");
+    option.value = String(tab.tabID);
+    option.textContent = tabLabel(tab);
+    option.title = tabTooltip(tab);
+    tabSelect.append(option);
+  });
+  Events.On("TabNavigated", function(event) {
+    const tab = new TabEvent(event.data).tab;
+    const option = tabSelect.querySelector(`option[value="${tab.tabID}"]`);
+    if (option instanceof HTMLOptionElement) {
+      option.textContent = tabLabel(tab);
+      option.title = tabTooltip(tab);
+    }
+  });
+  Events.On("TabClosed", function(event) {
+    const tab = new TabEvent(event.data).tab;
+    tabDetails.delete(tab.tabID);
+    const option = tabSelect.querySelector(`option[value="${tab.tabID}"]`);
+    if (option instanceof HTMLOptionElement) {
+      option.remove();
+    }
+    document.dispatchEvent(new Event("TabSelected", { bubbles: true }));
+  });
+  tabSelect.addEventListener("change", async function() {
+    document.dispatchEvent(new Event("TabSelected", { bubbles: true }));
+    await Backend.ActivateTab(Number(tabSelect.value));
+  });
+  document.addEventListener("NewTab", async function() {
+    const tab = await Backend.NewTab(profileSelect.value || "default", "");
+    tabSelect.value = String(tab.tabID);
+  });
+  document.addEventListener("CloseTab", async function() {
+    if (tabSelect.selectedOptions.length == 0 || tabSelect.selectedOptions[0].disabled) {
+      return;
+    }
+    await Backend.CloseTab(Number(tabSelect.value));
+  });
+
+  const launchModeSelect = document.getElementById("launchModeSelect");
+  if (!(launchModeSelect instanceof HTMLSelectElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const browserNameSelect = document.getElementById("browserNameSelect");
+  if (!(browserNameSelect instanceof HTMLSelectElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const headlessCheckbox = document.getElementById("headlessCheckbox");
+  if (!(headlessCheckbox instanceof HTMLInputElement)) {
+    throw new Error("element not found or invalid");
+  }
+  const autoReconnectCheckbox = document.getElementById("autoReconnectCheckbox");
+  if (!(autoReconnectCheckbox instanceof HTMLInputElement)) {
+    throw new Error("element not found or invalid");
+  }
+  launchModeSelect.addEventListener("change", function() {
+    // Only Playwright's own builds can be Firefox/WebKit or run headless.
+    const persistent = launchModeSelect.value == "persistent";
+    browserNameSelect.disabled = !persistent;
+    headlessCheckbox.disabled = !persistent;
+    if (!persistent) {
+      browserNameSelect.value = "chromium";
+      headlessCheckbox.checked = false;
+    }
   });
 
   document.addEventListener("Connect", async function() {
-    await Backend.Dialog(new MessageDialogOptions({
-      Title: "Info",
-      Message: "Hi there",
-    }));
+    try {
+      await Backend.OpenBrowser(new OpenBrowserOptions({
+        ProfileName: profileSelect.value || "default",
+        LaunchMode: launchModeSelect.value,
+        BrowserName: browserNameSelect.value,
+        Headless: headlessCheckbox.checked,
+        AutoReconnect: autoReconnectCheckbox.checked,
+      }));
+    } catch (err) {
+      await Backend.Dialog(new MessageDialogOptions({
+        Title: "Error",
+        Message: err instanceof Error ? err.message : String(err),
+      }));
+    } finally {
+      document.dispatchEvent(new Event("ProfilesChanged", { bubbles: true }));
+    }
+  });
+
+  document.addEventListener("Disconnect", async function() {
+    try {
+      await Backend.CloseBrowser(profileSelect.value || "default");
+    } catch (err) {
+      await Backend.Dialog(new MessageDialogOptions({
+        Title: "Error",
+        Message: err instanceof Error ? err.message : String(err),
+      }));
+    } finally {
+      document.dispatchEvent(new Event("ProfilesChanged", { bubbles: true }));
+    }
   });
 
 
@@ -135,6 +487,70 @@ try {
   if (!(textarea instanceof HTMLTextAreaElement)) {
     throw new Error("element not found or invalid");
   }
+
+  const flowSelect = document.getElementById("flowSelect");
+  if (!(flowSelect instanceof HTMLSelectElement)) {
+    throw new Error("element not found or invalid");
+  }
+  initEvents.add("FlowsChanged");
+  document.addEventListener("FlowsChanged", async function() {
+    const flows = await Backend.ListFlows();
+    const selectedFlowName = flowSelect.value;
+    for (const option of Array.from(flowSelect.options)) {
+      if (!option.disabled) {
+        option.remove();
+      }
+    }
+    for (const flow of flows) {
+      const option = document.createElement("option");
+      option.value = flow.name;
+      // Flows that could not be loaded are listed with their error.
+      option.textContent = flow.error ? `${flow.name} (invalid)` : flow.name;
+      option.title = flow.error || flow.description;
+      option.selected = flow.name == selectedFlowName;
+      flowSelect.append(option);
+    }
+  });
+  flowSelect.addEventListener("focus", function() {
+    // Flows are edited outside the app, pick up new files.
+    document.dispatchEvent(new Event("FlowsChanged", { bubbles: true }));
+  });
+  document.addEventListener("RunFlow", async function() {
+    if (flowSelect.selectedOptions.length == 0 || flowSelect.selectedOptions[0].disabled) {
+      return;
+    }
+    if (tabSelect.selectedOptions.length == 0 || tabSelect.selectedOptions[0].disabled) {
+      return;
+    }
+    try {
+      let flowRun = await Backend.StartFlow(flowSelect.value, Number(tabSelect.value));
+      while (flowRun.status == "running") {
+        await new Promise(function(resolve) { setTimeout(resolve, 500); });
+        flowRun = await Backend.FlowRun(flowRun.runID);
+      }
+      textarea.value = `${flowRun.flowName}: ${flowRun.status}\n`;
+      for (const [i, stepResult] of flowRun.steps.entries()) {
+        textarea.value += `${i + 1}. ${stepResult.step.action} ${stepResult.status} ${stepResult.message}\n`;
+      }
+      for (const [name, value] of Object.entries(flowRun.outputs)) {
+        textarea.value += `${name} = ${value}\n`;
+      }
+    } catch (err) {
+      await Backend.Dialog(new MessageDialogOptions({
+        Title: "Error",
+        Message: err instanceof Error ? err.message : String(err),
+      }));
+    }
+  });
+
+  document.addEventListener("OpenSettings", async function() {
+    await Backend.CreateWindow(new WebviewWindowOptions({
+      Name: "settings",
+      Title: "Settings",
+      URL: "/settings.html",
+    }));
+  });
+
   document.addEventListener("InstallDriver", async function() {
     const windowName = await Window.Name();
     const promise = fetch(`/backend/installdriver/?windowName=${windowName}`, { method: "POST" });
@@ -149,13 +565,18 @@ try {
         return;
       }
       const installDriverEvent = new InstallDriverEvent(event.data);
+      if (installDriverEvent.category == "progress") {
+        return;
+      }
       textarea.value += `${installDriverEvent.category}: ${installDriverEvent.message}\n`;
       if (stickToBottom) {
         textarea.scrollTop = textarea.scrollHeight;
       }
     });
     try {
-      await promise;
+      // The response streams the same events, wait for it to end.
+      const response = await promise;
+      await response.text();
     } finally {
       unregister();
       textarea.removeEventListener("scroll", updateStickToBottom);
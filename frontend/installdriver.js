@@ -1,4 +1,4 @@
-import { Events, Window } from "@wailsio/runtime";
+import { Window } from "@wailsio/runtime";
 import { Backend, InstallDriverEvent } from "./bindings/changeme";
 
 const state = {
@@ -41,9 +41,12 @@ document.addEventListener("Render", function() {
 document.addEventListener("InstallDriver", function() {
   installDriverButton.disabled = true;
 });
-document.addEventListener("InstallDriverDone", function() {
+document.addEventListener("InstallDriverDone", function(event) {
   installDriverButton.disabled = false;
-  installDriverButton.style.display = "none";
+  // Keep the button around to try again if the install did not succeed.
+  if (event instanceof CustomEvent && event.detail.category == "done") {
+    installDriverButton.style.display = "none";
+  }
 });
 
 const installDriverButtonSpinner = installDriverButton.querySelector("svg");
@@ -77,32 +80,138 @@ const textarea = document.getElementById("textarea");
 if (!(textarea instanceof HTMLTextAreaElement)) {
   throw new Error("element not found or invalid");
 }
-document.addEventListener("InstallDriver", async function() {
-  const windowName = await Window.Name();
-  const promise = fetch(`/backend/installdriver/?windowName=${windowName}`, { method: "POST" });
+const browserCheckboxes = document.getElementById("browserCheckboxes");
+if (!(browserCheckboxes instanceof HTMLElement)) {
+  throw new Error("element not found or invalid");
+}
+document.addEventListener("InstallDriver", function() {
+  for (const checkbox of browserCheckboxes.querySelectorAll("input[name=browser]")) {
+    if (checkbox instanceof HTMLInputElement) {
+      checkbox.disabled = true;
+    }
+  }
+});
+document.addEventListener("InstallDriverDone", function() {
+  for (const checkbox of browserCheckboxes.querySelectorAll("input[name=browser]")) {
+    if (checkbox instanceof HTMLInputElement) {
+      checkbox.disabled = false;
+    }
+  }
+});
+
+const allowUnverifiedBox = document.getElementById("allowUnverifiedBox");
+if (!(allowUnverifiedBox instanceof HTMLElement)) {
+  throw new Error("element not found or invalid");
+}
+const allowUnverified = document.getElementById("allowUnverified");
+if (!(allowUnverified instanceof HTMLInputElement)) {
+  throw new Error("element not found or invalid");
+}
+document.addEventListener("InstallDriver", function() {
+  allowUnverified.disabled = true;
+});
+document.addEventListener("InstallDriverDone", function() {
+  allowUnverified.disabled = false;
+});
+
+/**
+ * Reads the InstallDriverEvents streamed by /backend/installdriver/ and
+ * reconnects with the last event ID if the stream ends before the install
+ * does.
+ * @param {Response} response
+ */
+async function watchInstall(response) {
   let stickToBottom = true;
   const updateStickToBottom = function() {
     stickToBottom = textarea.scrollHeight - textarea.scrollTop - textarea.clientHeight <= 50 /* px tolerance */;
   }
   textarea.addEventListener("scroll", updateStickToBottom);
   textarea.value = "";
-  const unregister = Events.On("InstallDriverEvent", function(event) {
-    if (event.sender != windowName) {
-      return;
-    }
-    const installDriverEvent = new InstallDriverEvent(event.data);
-    textarea.value += `${installDriverEvent.category}: ${installDriverEvent.message}\n`;
-    if (stickToBottom) {
-      textarea.scrollTop = textarea.scrollHeight;
-    }
-  });
+  let lastEventID = "";
+  let lastCategory = "";
+  let finished = false;
   try {
-    await promise;
+    while (!finished) {
+      if (!response.ok || !response.body) {
+        throw new Error(await response.text());
+      }
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      try {
+        while (!finished) {
+          const { value, done } = await reader.read();
+          if (done) {
+            break;
+          }
+          buffer += value;
+          let index;
+          while ((index = buffer.indexOf("\n")) >= 0) {
+            const line = buffer.slice(0, index);
+            buffer = buffer.slice(index + 1);
+            if (line == "") {
+              continue;
+            }
+            const installDriverEvent = new InstallDriverEvent(JSON.parse(line));
+            lastEventID = installDriverEvent.eventID;
+            lastCategory = installDriverEvent.category;
+            if (installDriverEvent.category == "progress") {
+              if (installDriverEvent.total > 0) {
+                infoMessage.textContent = `Downloading... ${Math.floor(installDriverEvent.bytes * 100 / installDriverEvent.total)}%`;
+              }
+              continue;
+            }
+            textarea.value += `${installDriverEvent.category}: ${installDriverEvent.message}\n`;
+            if (installDriverEvent.category == "done" || installDriverEvent.category == "error" || installDriverEvent.category == "checksum-mismatch") {
+              finished = true;
+            }
+            if (installDriverEvent.category == "checksum-unknown") {
+              // Only install an unverified driver if the user says so.
+              allowUnverifiedBox.style.display = "";
+              finished = true;
+            }
+          }
+          if (stickToBottom) {
+            textarea.scrollTop = textarea.scrollHeight;
+          }
+        }
+      } catch (err) {
+        console.error(err);
+      }
+      if (!finished) {
+        await new Promise(function(resolve) { setTimeout(resolve, 1000); });
+        response = await fetch(`/backend/installdriver/?lastEventID=${encodeURIComponent(lastEventID)}`);
+      }
+    }
   } finally {
-    unregister();
     textarea.removeEventListener("scroll", updateStickToBottom);
-    document.dispatchEvent(new Event("InstallDriverDone", { bubbles: true }));
+    document.dispatchEvent(new CustomEvent("InstallDriverDone", { bubbles: true, detail: { category: lastCategory } }));
+  }
+}
+
+document.addEventListener("InstallDriver", async function() {
+  const params = new URLSearchParams();
+  params.append("windowName", await Window.Name());
+  if (allowUnverified.checked) {
+    params.append("allowUnverified", "true");
   }
+  for (const checkbox of browserCheckboxes.querySelectorAll("input[name=browser]:checked")) {
+    if (checkbox instanceof HTMLInputElement) {
+      params.append("browser", checkbox.value);
+    }
+  }
+  await watchInstall(await fetch(`/backend/installdriver/?${params.toString()}`, { method: "POST" }));
 });
 
+// If the window was closed during an install, pick it up where it is.
+(async function resumeInstall() {
+  const response = await fetch("/backend/installdriver/");
+  if (response.status == 404) {
+    return;
+  }
+  installDriverButton.disabled = true;
+  installDriverButtonSpinner.style.display = "";
+  infoMessage.textContent = "Installing...";
+  await watchInstall(response);
+})();
+
 state.init();
@@ -0,0 +1,74 @@
+import { Events } from "@wailsio/runtime";
+import { Backend, StartupStatus } from "./bindings/changeme";
+
+const errorMessage = document.getElementById("errorMessage");
+if (!(errorMessage instanceof HTMLElement)) {
+  throw new Error("element not found or invalid");
+}
+const driversDirectoryInput = document.getElementById("driversDirectoryInput");
+if (!(driversDirectoryInput instanceof HTMLInputElement)) {
+  throw new Error("element not found or invalid");
+}
+const retryButton = document.getElementById("retryButton");
+if (!(retryButton instanceof HTMLButtonElement)) {
+  throw new Error("element not found or invalid");
+}
+const resetSettingsButton = document.getElementById("resetSettingsButton");
+if (!(resetSettingsButton instanceof HTMLButtonElement)) {
+  throw new Error("element not found or invalid");
+}
+const infoMessage = document.getElementById("infoMessage");
+if (!(infoMessage instanceof HTMLElement)) {
+  throw new Error("element not found or invalid");
+}
+
+/**
+ * @param {StartupStatus} startupStatus
+ */
+function render(startupStatus) {
+  errorMessage.textContent = startupStatus.error;
+  retryButton.disabled = startupStatus.state == "starting";
+  resetSettingsButton.disabled = startupStatus.state == "starting";
+  resetSettingsButton.style.display = startupStatus.settingsInvalid ? "" : "none";
+  infoMessage.textContent = startupStatus.state == "starting" ? "Starting..." : "";
+  if (driversDirectoryInput.value == "") {
+    driversDirectoryInput.value = startupStatus.driversDirectory;
+  }
+}
+
+Events.On("StartupStatus", function(event) {
+  render(new StartupStatus(event.data));
+});
+
+document.addEventListener("ChooseDriversDirectory", async function() {
+  const directory = await Backend.ChooseDirectory("Choose drivers folder", driversDirectoryInput.value);
+  if (directory != "") {
+    driversDirectoryInput.value = directory;
+  }
+});
+
+document.addEventListener("RetryStartup", async function() {
+  try {
+    // On success the backend closes this window.
+    const startupStatus = await Backend.StartupStatus();
+    // Only save the drivers folder in the settings if it was changed.
+    const driversDirectory = driversDirectoryInput.value == startupStatus.driversDirectory ? "" : driversDirectoryInput.value;
+    await Backend.RetryStartup(driversDirectory);
+  } catch (err) {
+    errorMessage.textContent = err instanceof Error ? err.message : String(err);
+  }
+});
+
+document.addEventListener("ResetSettings", async function() {
+  try {
+    // The invalid settings are kept in settings.json.invalid.
+    await Backend.ResetSettings();
+    await Backend.RetryStartup(driversDirectoryInput.value);
+  } catch (err) {
+    errorMessage.textContent = err instanceof Error ? err.message : String(err);
+  }
+});
+
+(async function init() {
+  render(await Backend.StartupStatus());
+})();
@@ -0,0 +1,93 @@
+import { Events, Window } from "@wailsio/runtime";
+import { Backend, NetworkSettings, Settings, SettingsInfo } from "./bindings/changeme";
+
+const settingsForm = document.getElementById("settingsForm");
+if (!(settingsForm instanceof HTMLFormElement)) {
+  throw new Error("element not found or invalid");
+}
+const infoMessage = document.getElementById("infoMessage");
+if (!(infoMessage instanceof HTMLElement)) {
+  throw new Error("element not found or invalid");
+}
+
+/**
+ * Fills in the form with the saved settings. Empty fields show the value in
+ * effect as their placeholder, overridden fields are disabled.
+ * @param {SettingsInfo} settingsInfo
+ */
+function render(settingsInfo) {
+  for (const input of settingsForm.querySelectorAll("input[name]")) {
+    const saved = settingsInfo.saved[input.name];
+    const effective = settingsInfo.effective[input.name];
+    input.value = Array.isArray(saved) ? saved.join(",") : saved ? String(saved) : "";
+    input.placeholder = Array.isArray(effective) ? effective.join(",") : String(effective);
+    const override = settingsInfo.overrides[input.name];
+    input.disabled = override != undefined;
+    input.title = override != undefined ? `set by ${override}` : "";
+  }
+  const proxyPassword = settingsForm.elements.namedItem("proxyPassword");
+  if (proxyPassword instanceof HTMLInputElement && settingsInfo.proxyPasswordSet) {
+    proxyPassword.placeholder = "saved, leave empty to keep";
+  }
+  infoMessage.textContent = settingsInfo.restartRequired ? "Restart the app for the changes to take effect" : "";
+}
+
+Events.On("SettingsChanged", function(event) {
+  render(new SettingsInfo(event.data));
+});
+
+/**
+ * @param {FormData} formData
+ * @returns {NetworkSettings}
+ */
+function networkSettings(formData) {
+  return new NetworkSettings({
+    proxyURL: String(formData.get("proxyURL") || ""),
+    proxyUsername: String(formData.get("proxyUsername") || ""),
+    proxyPassword: String(formData.get("proxyPassword") || ""),
+    noProxy: String(formData.get("noProxy") || ""),
+    caBundleFile: String(formData.get("caBundleFile") || ""),
+  });
+}
+
+document.addEventListener("SaveSettings", async function() {
+  const formData = new FormData(settingsForm);
+  const downloadMirrors = String(formData.get("downloadMirrors") || "");
+  try {
+    await Backend.UpdateSettings(new Settings({
+      ...networkSettings(formData),
+      dataDirectory: String(formData.get("dataDirectory") || ""),
+      driversDirectory: String(formData.get("driversDirectory") || ""),
+      browsersDirectory: String(formData.get("browsersDirectory") || ""),
+      profilesDirectory: String(formData.get("profilesDirectory") || ""),
+      httpAddress: String(formData.get("httpAddress") || ""),
+      defaultCDPPort: Number(formData.get("defaultCDPPort") || 0),
+      downloadMirrors: downloadMirrors == "" ? [] : downloadMirrors.split(",").map(s => s.trim()),
+    }));
+  } catch (err) {
+    infoMessage.textContent = err instanceof Error ? err.message : String(err);
+  }
+});
+
+document.addEventListener("CheckNetworkSettings", async function() {
+  infoMessage.textContent = "Checking...";
+  try {
+    await Backend.CheckNetworkSettings(networkSettings(new FormData(settingsForm)));
+    infoMessage.textContent = "The download host can be reached";
+  } catch (err) {
+    infoMessage.textContent = err instanceof Error ? err.message : String(err);
+  }
+});
+
+document.addEventListener("CloseWindow", async function() {
+  await Backend.CloseWindow(await Window.Name());
+});
+
+(async function init() {
+  render(await Backend.GetSettings());
+  const controlAPIToken = document.getElementById("controlAPIToken");
+  if (!(controlAPIToken instanceof HTMLInputElement)) {
+    throw new Error("element not found or invalid");
+  }
+  controlAPIToken.value = await Backend.ControlAPIToken();
+})();
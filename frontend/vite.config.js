@@ -15,6 +15,8 @@ export default defineConfig({
         // https://vite.dev/guide/build.html#multi-page-app
         index: resolve(__dirname, "index.html"),
         installdriver: resolve(__dirname, "installdriver.html"),
+        recovery: resolve(__dirname, "recovery.html"),
+        settings: resolve(__dirname, "settings.html"),
       }
     }
   },
@@ -2,15 +2,22 @@ package main
 
 import (
 	"archive/zip"
+	"bufio"
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -20,75 +27,295 @@ func init() {
 	application.RegisterEvent[InstallDriverEvent]("InstallDriverEvent")
 }
 
+// InstallDriverEvent is one step of a driver install. Category is one of:
+//
+//   - "started": the install has begun, Message is the process ID.
+//   - "info": a log line.
+//   - "progress": Bytes of Total bytes of the driver archive have been
+//     downloaded (Total is -1 if unknown).
+//   - "file": FileName (Bytes bytes) is being unzipped.
+//   - "browser": a line of output from the browser install.
+//   - "done": the install succeeded.
+//   - "error": the install failed or was cancelled.
+//   - "checksum-mismatch": the downloaded driver archive does not match its
+//     checksum. It has been deleted, the next install downloads it again.
+//   - "checksum-unknown": no checksum is known for the driver archive, it
+//     can be installed by starting the install again with allowUnverified.
+//
+// "done", "error", "checksum-mismatch" and "checksum-unknown" are always the
+// last event of an install.
 type InstallDriverEvent struct {
 	EventID   string `json:"eventID"`
 	Category  string `json:"category"`
 	Message   string `json:"message"`
+	Bytes     int64  `json:"bytes,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	FileName  string `json:"fileName,omitempty"`
 	Timestamp int64  `json:"timestamp"`
 }
 
-// playwrightCDNMirrors is copied from playwright-go.
+// isFinalInstallDriverEvent reports whether an InstallDriverEvent category
+// ends an install.
+func isFinalInstallDriverEvent(category string) bool {
+	switch category {
+	case "done", "error", "checksum-mismatch", "checksum-unknown":
+		return true
+	}
+	return false
+}
+
+// DriverInstall is a driver install running in the background. It keeps its
+// events so that a client can attach at any time, e.g. after the
+// installdriver window was closed and reopened, and replay what it missed.
+type DriverInstall struct {
+	InstallID       string
+	WindowName      string
+	Browsers        []string
+	AllowUnverified bool
+
+	mutex    sync.Mutex
+	sequence int
+	events   []InstallDriverEvent
+	done     bool
+	changed  chan struct{}
+}
+
+// emit records an event, assigning it the next event ID. Consecutive
+// progress events replace each other so that the log stays small, a client
+// replaying it only needs the latest progress.
+func (install *DriverInstall) emit(event InstallDriverEvent) InstallDriverEvent {
+	install.mutex.Lock()
+	defer install.mutex.Unlock()
+	install.sequence++
+	event.EventID = install.InstallID + "-" + strconv.Itoa(install.sequence)
+	event.Timestamp = time.Now().Unix()
+	if n := len(install.events); event.Category == "progress" && n > 0 && install.events[n-1].Category == "progress" {
+		install.events[n-1] = event
+	} else {
+		install.events = append(install.events, event)
+	}
+	if isFinalInstallDriverEvent(event.Category) {
+		install.done = true
+	}
+	close(install.changed)
+	install.changed = make(chan struct{})
+	return event
+}
+
+// isDone reports whether the install is over.
+func (install *DriverInstall) isDone() bool {
+	install.mutex.Lock()
+	defer install.mutex.Unlock()
+	return install.done
+}
+
+// eventsAfter returns the events following the event with lastEventID, or
+// all events if lastEventID is empty or belongs to another install. It also
+// reports whether the install is over and returns a channel that is closed
+// when the next event is recorded.
+func (install *DriverInstall) eventsAfter(lastEventID string) ([]InstallDriverEvent, bool, <-chan struct{}) {
+	install.mutex.Lock()
+	defer install.mutex.Unlock()
+	lastSequence := 0
+	if s, ok := strings.CutPrefix(lastEventID, install.InstallID+"-"); ok {
+		lastSequence, _ = strconv.Atoi(s)
+	}
+	var events []InstallDriverEvent
+	for _, event := range install.events {
+		sequence, _ := strconv.Atoi(strings.TrimPrefix(event.EventID, install.InstallID+"-"))
+		if sequence > lastSequence {
+			events = append(events, event)
+		}
+	}
+	return events, install.done, install.changed
+}
+
+// playwrightCDNMirrors is copied from playwright-go. They are the default
+// Settings.DownloadMirrors.
 var playwrightCDNMirrors = []string{
 	"https://playwright.azureedge.net",
 	"https://playwright-akamai.azureedge.net",
 	"https://playwright-verizon.azureedge.net",
 }
 
+// installdriver serves the driver install as a stream of InstallDriverEvents.
+//
+// POST /installdriver/ (form fields: windowName, browser, allowUnverified)
+// starts an install, or attaches to the one already running. GET
+// /installdriver/ attaches to the running or most recent install and
+// responds 404 if there is none. To resume watching, pass the last event ID
+// seen in the Last-Event-ID header or the lastEventID form field.
+//
+// The install keeps running if the client goes away, it can only be stopped
+// with CancelProcess. Events are written as Server-Sent Events if the client
+// accepts text/event-stream and as newline-delimited JSON otherwise.
 func (backend *Backend) installdriver(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
+	var install *DriverInstall
+	switch r.Method {
+	case "GET":
+		backend.Mutex.Lock()
+		install = backend.DriverInstall
+		backend.Mutex.Unlock()
+		if install == nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+	case "POST":
+		windowName := r.Form.Get("windowName")
+		if windowName == "" {
+			http.Error(w, "MissingWindowName", http.StatusBadRequest)
+			return
+		}
+		browsers := r.Form["browser"]
+		for _, browser := range browsers {
+			if browser != BrowserChromium && browser != BrowserFirefox && browser != BrowserWebKit {
+				http.Error(w, "InvalidBrowser", http.StatusBadRequest)
+				return
+			}
+		}
+		allowUnverified, _ := strconv.ParseBool(r.Form.Get("allowUnverified"))
+		var err error
+		install, err = backend.startDriverInstall(windowName, browsers, allowUnverified)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	windowName := r.Form.Get("windowName")
-	if windowName == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintln(w, "MissingWindowName")
-		return
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.Form.Get("lastEventID")
 	}
+	eventStream := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if eventStream {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
 	responseController := http.NewResponseController(w)
-	writeResponse := func(w http.ResponseWriter, category string, message string) {
-		fmt.Fprintln(w, category+": "+message)
+	for {
+		events, done, changed := install.eventsAfter(lastEventID)
+		for _, event := range events {
+			b, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if eventStream {
+				_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.EventID, event.Category, b)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", b)
+			}
+			if err != nil {
+				return
+			}
+			lastEventID = event.EventID
+		}
 		responseController.Flush()
-		backend.App.Event.EmitEvent(&application.CustomEvent{
-			Sender: windowName,
-			Name:   "InstallDriverEvent",
-			Data: InstallDriverEvent{
-				Category:  category,
-				Message:   message,
-				Timestamp: time.Now().Unix(),
-			},
-		})
-	}
-	platform := ""
-	switch runtime.GOOS {
-	case "windows":
-		platform = "win32_x64"
-	case "darwin":
-		if runtime.GOARCH == "arm64" {
-			platform = "mac-arm64"
-		} else {
-			platform = "mac"
+		if done {
+			return
 		}
-	case "linux":
-		if runtime.GOARCH == "arm64" {
-			platform = "linux-arm64"
-		} else {
-			platform = "linux"
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// beginDriverOperation reserves the driver and downloads directories for a
+// driver import or cleanup and returns a function that releases them. An
+// import, a cleanup and an install all stage, swap or remove files in those
+// directories, only one of them may run at a time.
+func (backend *Backend) beginDriverOperation(operation string) (func(), error) {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	if install := backend.DriverInstall; install != nil && !install.isDone() {
+		return nil, fmt.Errorf("a driver install is in progress")
+	}
+	if backend.DriverOperation != "" {
+		return nil, fmt.Errorf("a driver %s is in progress", backend.DriverOperation)
+	}
+	backend.DriverOperation = operation
+	return func() {
+		backend.Mutex.Lock()
+		backend.DriverOperation = ""
+		backend.Mutex.Unlock()
+	}, nil
+}
+
+// startDriverInstall starts installing the driver (and browsers) in the
+// background, or returns the install that is already running. If
+// allowUnverified is set the driver archive is installed even if no checksum
+// is known for it. It fails while a driver import or cleanup is in progress,
+// see beginDriverOperation.
+func (backend *Backend) startDriverInstall(windowName string, browsers []string, allowUnverified bool) (*DriverInstall, error) {
+	backend.Mutex.Lock()
+	if install := backend.DriverInstall; install != nil && !install.isDone() {
+		backend.Mutex.Unlock()
+		return install, nil
+	}
+	if backend.DriverOperation != "" {
+		backend.Mutex.Unlock()
+		return nil, fmt.Errorf("a driver %s is in progress", backend.DriverOperation)
+	}
+	install := &DriverInstall{
+		InstallID:       "install-" + strconv.FormatInt(backend.Sequence.Add(1), 10),
+		WindowName:      windowName,
+		Browsers:        browsers,
+		AllowUnverified: allowUnverified,
+		changed:         make(chan struct{}),
+	}
+	backend.DriverInstall = install
+	backend.Mutex.Unlock()
+	// Not derived from the request context, the install outlives the
+	// request that started it.
+	process := backend.beginProcess(context.Background(), windowName, "Installing Playwright driver", 0)
+	go backend.runDriverInstall(install, process)
+	return install, nil
+}
+
+// runDriverInstall downloads, verifies and installs the driver archive, then
+// installs the requested browsers.
+func (backend *Backend) runDriverInstall(install *DriverInstall, process *Process) {
+	ctx := process.Context()
+	browsers := install.Browsers
+	emitEvent := func(event InstallDriverEvent) {
+		switch event.Category {
+		case "error", "checksum-mismatch", "checksum-unknown":
+			if ctx.Err() != nil {
+				process.Fail(ctx.Err())
+			} else {
+				process.Fail(errors.New(event.Message))
+			}
+		case "done":
+			process.Finish("Installed Playwright driver")
 		}
+		event = install.emit(event)
+		backend.emitEvent(&application.CustomEvent{
+			Sender: install.WindowName,
+			Name:   "InstallDriverEvent",
+			Data:   event,
+		})
 	}
-	err := os.MkdirAll(backend.PlaywrightRunOptions.DriverDirectory, 0755)
+	emitEvent(InstallDriverEvent{Category: "started", Message: process.ProcessID()})
+	platform := driverPlatform()
+	// Archives are kept outside of the driver directory, which is replaced
+	// as a whole.
+	err := os.MkdirAll(backend.DownloadsDirectory, 0755)
 	if err != nil {
-		writeResponse(w, "error", fmt.Sprintf("creating directory %s: %v", backend.PlaywrightRunOptions.DriverDirectory, err))
+		emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("creating directory %s: %v", backend.DownloadsDirectory, err)})
 		return
 	}
-	baseName := fmt.Sprintf("playwright-%s-%s.zip", backend.PlaywrightDriver.Version, platform)
-	filePath := filepath.Join(backend.PlaywrightRunOptions.DriverDirectory, baseName)
+	baseName := driverArchiveName(backend.PlaywrightDriver.Version, platform)
+	filePath := filepath.Join(backend.DownloadsDirectory, baseName)
 	fileInfo, err := os.Stat(filePath)
 	needDownloadFile := false
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
-			writeResponse(w, "error", fmt.Sprintf("fetching file info for %s: %v", filePath, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("fetching file info for %s: %v", filePath, err)})
 			return
 		}
 		needDownloadFile = true
@@ -104,141 +331,253 @@ func (backend *Backend) installdriver(w http.ResponseWriter, r *http.Request) {
 		} else {
 			pathName = "/builds/driver/next/" + baseName
 		}
-		var origins []string
-		if s := os.Getenv("PLAYWRIGHT_DOWNLOAD_HOST"); s != "" {
-			origins = []string{s}
-		} else {
-			origins = playwrightCDNMirrors
-		}
-		var successfulResponse *http.Response
-		httpClient := &http.Client{
-			Timeout: 5 * time.Minute,
-		}
+		origins := backend.settings().DownloadMirrors
+		downloadURLs := make([]string, 0, len(origins))
 		for _, origin := range origins {
-			downloadURL := origin + pathName
-			writeResponse(w, "info", fmt.Sprintf("attempting to download from %s", downloadURL))
-			req, err := http.NewRequest("GET", downloadURL, nil)
-			if err != nil {
-				writeResponse(w, "info", fmt.Sprintf("info: GET %s: %v", downloadURL, err))
-				continue
-			}
-			resp, err := httpClient.Do(req)
-			if err != nil {
-				writeResponse(w, "info", fmt.Sprintf("info: GET %s: %v", downloadURL, err))
-				continue
-			}
-			if resp.StatusCode != http.StatusOK {
-				resp.Body.Close()
-				writeResponse(w, "info", fmt.Sprintf("GET %s: non 200 status code %d (%s)", downloadURL, resp.StatusCode, resp.Status))
-				continue
-			}
-			successfulResponse = resp
-			break
-		}
-		if successfulResponse == nil {
-			writeResponse(w, "error", fmt.Sprintf("error: failed to download %s from all playwright origins", baseName))
-			return
+			downloadURLs = append(downloadURLs, origin+pathName)
 		}
-		writeResponse(w, "info", fmt.Sprintf("downloading from %s", successfulResponse.Request.URL.String()))
-		defer successfulResponse.Body.Close()
-		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		// No overall timeout, slow links are fine as long as data keeps
+		// arriving (see downloadStallTimeout).
+		httpClient, err := backend.httpClient()
 		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("opening file for writing %s: %v", filePath, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("network settings: %v", err)})
 			return
 		}
-		defer file.Close()
-		var buf [32 * 1024]byte
-		var written int64
-		for {
-			bytesRead, readErr := successfulResponse.Body.Read(buf[:])
-			if bytesRead > 0 {
-				bytesWritten, writeErr := file.Write(buf[:bytesRead])
-				written += int64(bytesWritten)
-				writeResponse(w, "downloading", fmt.Sprintf("%d", written))
-				if writeErr != nil {
-					writeResponse(w, "error", fmt.Sprintf("downloading to %s: %v", filePath, writeErr))
-					return
-				}
-			}
-			if readErr != nil {
-				if readErr != io.EOF {
-					writeResponse(w, "error", fmt.Sprintf("downloading from %s: %v", successfulResponse.Request.URL.String(), readErr))
-					return
-				}
-				writeResponse(w, "downloaded", fmt.Sprintf("%d %s", written, filePath))
-				break
+		err = resumableDownload(ctx, httpClient, downloadURLs, filePath, func(message string) {
+			emitEvent(InstallDriverEvent{Category: "info", Message: message})
+		}, func(written, total int64) {
+			message := fmt.Sprintf("Downloading %s (%s)", baseName, HumanReadableFileSize(written))
+			if total > 0 {
+				message = fmt.Sprintf("Downloading %s (%s of %s)", baseName, HumanReadableFileSize(written), HumanReadableFileSize(total))
 			}
+			emitEvent(InstallDriverEvent{Category: "progress", Message: message, Bytes: written, Total: total})
+			process.SetProgress(message, int(written), int(total))
+		})
+		if err != nil {
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("downloading %s: %v", baseName, err)})
+			return
 		}
-		err = file.Close()
+		fileInfo, err := os.Stat(filePath)
 		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("saving to %s: %v", filePath, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("fetching file info for %s: %v", filePath, err)})
 			return
 		}
+		emitEvent(InstallDriverEvent{Category: "info", Message: fmt.Sprintf("downloaded %s (%s)", filePath, HumanReadableFileSize(fileInfo.Size()))})
 	}
-	file, err := os.Open(filePath)
+	process.SetMessage(fmt.Sprintf("Verifying %s", baseName))
+	checksum, err := backend.verifyDriverArchive(backend.PlaywrightDriver.Version, platform, filePath, install.AllowUnverified)
 	if err != nil {
-		writeResponse(w, "error", fmt.Sprintf("opening file for reading %s: %v", filePath, err))
+		var unknownErr *ChecksumUnknownError
+		var mismatchErr *ChecksumMismatchError
+		switch {
+		case errors.As(err, &unknownErr):
+			emitEvent(InstallDriverEvent{Category: "checksum-unknown", Message: err.Error()})
+		case errors.As(err, &mismatchErr):
+			// Remove the bad file so that the next attempt downloads it
+			// again.
+			os.Remove(filePath)
+			emitEvent(InstallDriverEvent{Category: "checksum-mismatch", Message: err.Error()})
+		default:
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("verifying %s: %v", filePath, err)})
+		}
 		return
 	}
-	defer file.Close()
-	fileInfo, err = file.Stat()
-	if err != nil {
-		writeResponse(w, "error", fmt.Sprintf("fetching file info for %s: %v", filePath, err))
-		return
+	if checksum == "" {
+		emitEvent(InstallDriverEvent{Category: "info", Message: fmt.Sprintf("no checksum known for %s, installing it unverified as requested", baseName)})
+	} else {
+		emitEvent(InstallDriverEvent{Category: "info", Message: fmt.Sprintf("verified %s (sha256 %s)", baseName, checksum)})
 	}
-	zipReader, err := zip.NewReader(file, fileInfo.Size())
+	err = backend.installDriverArchive(ctx, filePath, func(zipFile *zip.File, index int, count int) {
+		emitEvent(InstallDriverEvent{Category: "file", Message: zipFile.Name, FileName: zipFile.Name, Bytes: int64(zipFile.UncompressedSize64)})
+		process.SetProgress(fmt.Sprintf("Unzipping %s", baseName), index, count)
+	})
 	if err != nil {
-		writeResponse(w, "error", fmt.Sprintf("reading zip file %s: %v", filePath, err))
+		emitEvent(InstallDriverEvent{Category: "error", Message: err.Error()})
 		return
 	}
-	for _, zipFile := range zipReader.File {
-		writeResponse(w, "unzipping", fmt.Sprintf("%d %s", zipFile.UncompressedSize64, zipFile.Name))
-		destFilePath := filepath.Join(backend.PlaywrightRunOptions.DriverDirectory, zipFile.Name)
-		if zipFile.FileInfo().IsDir() {
-			err := os.MkdirAll(destFilePath, 0755)
-			if err != nil {
-				writeResponse(w, "error", fmt.Sprintf("creating folder %s: %v", destFilePath, err))
-				return
-			}
-			continue
-		}
-		srcFile, err := zipFile.Open()
+	if len(browsers) > 0 {
+		// The driver knows which browser builds match its version, let it
+		// download them into PLAYWRIGHT_BROWSERS_PATH.
+		cmd := backend.PlaywrightDriver.Command(append([]string{"install"}, browsers...)...)
+		err = backend.settings().NetworkSettings.applyToCommand(cmd)
 		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("opening file for reading %s: %v", zipFile.Name, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("network settings: %v", err)})
 			return
 		}
-		destFile, err := os.OpenFile(destFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		stdout, err := cmd.StdoutPipe()
 		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("opening file for writing %s: %v", destFilePath, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("installing %s: %v", strings.Join(browsers, ", "), err)})
 			return
 		}
-		_, err = io.Copy(destFile, srcFile)
+		cmd.Stderr = cmd.Stdout
+		err = cmd.Start()
 		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("unzipping %s: %v", zipFile.Name, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("installing %s: %v", strings.Join(browsers, ", "), err)})
 			return
 		}
-		err = destFile.Close()
-		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("closing %s: %v", destFilePath, err))
-			return
+		stopKill := context.AfterFunc(ctx, func() {
+			cmd.Process.Kill()
+		})
+		defer stopKill()
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			emitEvent(InstallDriverEvent{Category: "browser", Message: line})
+			process.SetMessage(line)
 		}
-		err = srcFile.Close()
+		err = cmd.Wait()
 		if err != nil {
-			writeResponse(w, "error", fmt.Sprintf("closing %s: %v", zipFile.Name, err))
+			emitEvent(InstallDriverEvent{Category: "error", Message: fmt.Sprintf("installing %s: %v", strings.Join(browsers, ", "), err)})
 			return
 		}
-		if zipFile.Mode().Perm()&0111 != 0 && runtime.GOOS != "windows" {
-			fileInfo, err := os.Stat(destFilePath)
-			if err != nil {
-				writeResponse(w, "error", fmt.Sprintf("fetching file info for %s: %v", destFilePath, err))
-				return
-			}
-			err = os.Chmod(destFilePath, fileInfo.Mode()|0111)
-			if err != nil {
-				writeResponse(w, "error", fmt.Sprintf("making file executable %s: %v", destFilePath, err))
-				return
-			}
+		emitEvent(InstallDriverEvent{Category: "info", Message: fmt.Sprintf("installed %s", strings.Join(browsers, ", "))})
+	}
+	emitEvent(InstallDriverEvent{Category: "done", Message: fmt.Sprintf("installed Playwright driver %s", backend.PlaywrightDriver.Version)})
+}
+
+// driverPlatforms are all the platform names driverPlatform can return.
+// driverchecksums.json must have an entry for each of them.
+var driverPlatforms = []string{"win32_x64", "mac", "mac-arm64", "linux", "linux-arm64"}
+
+// driverPlatform returns the platform name used in Playwright driver archive
+// names for the current OS and architecture.
+func driverPlatform() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "win32_x64"
+	case "darwin":
+		if runtime.GOARCH == "arm64" {
+			return "mac-arm64"
+		}
+		return "mac"
+	case "linux":
+		if runtime.GOARCH == "arm64" {
+			return "linux-arm64"
+		}
+		return "linux"
+	}
+	return ""
+}
+
+// driverArchiveName returns the file name of the Playwright driver archive
+// for a version and platform.
+func driverArchiveName(version, platform string) string {
+	return fmt.Sprintf("playwright-%s-%s.zip", version, platform)
+}
+
+// installDriverArchive extracts a driver archive into a staging directory,
+// checks that it contains the driver version we need and only then swaps it
+// into place, so that a failed install leaves the previous driver intact.
+// The driver must not be running.
+func (backend *Backend) installDriverArchive(ctx context.Context, filePath string, onFile func(zipFile *zip.File, index int, count int)) error {
+	// node runs out of the driver directory, on Windows it can't be moved
+	// aside while it does.
+	if backend.Playwright != nil {
+		return fmt.Errorf("the driver is running, restart the app before installing a driver")
+	}
+	driverDirectory := backend.PlaywrightRunOptions.DriverDirectory
+	stagingDirectory := driverDirectory + ".staging"
+	err := os.RemoveAll(stagingDirectory)
+	if err != nil {
+		return fmt.Errorf("removing %s: %w", stagingDirectory, err)
+	}
+	err = extractZip(ctx, filePath, stagingDirectory, onFile)
+	if err != nil {
+		return fmt.Errorf("unzipping %s: %w", filePath, err)
+	}
+	version, err := readDriverVersion(stagingDirectory)
+	if err != nil {
+		os.RemoveAll(stagingDirectory)
+		return fmt.Errorf("%s does not contain a Playwright driver: %w", filePath, err)
+	}
+	if version != backend.PlaywrightDriver.Version {
+		os.RemoveAll(stagingDirectory)
+		return fmt.Errorf("%s contains driver version %s, need %s", filePath, version, backend.PlaywrightDriver.Version)
+	}
+	// The archive name says nothing once it has been renamed, check that
+	// the node binary it ships runs on this machine.
+	platform, err := readDriverPlatform(stagingDirectory)
+	if err != nil {
+		os.RemoveAll(stagingDirectory)
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if platform != driverPlatform() {
+		os.RemoveAll(stagingDirectory)
+		return fmt.Errorf("%s contains the driver for platform %s, need %s", filePath, platform, driverPlatform())
+	}
+	err = swapDirectory(stagingDirectory, driverDirectory)
+	if err != nil {
+		os.RemoveAll(stagingDirectory)
+		return err
+	}
+	return nil
+}
+
+// readDriverVersion returns the version of the driver unpacked in
+// driverDirectory.
+func readDriverVersion(driverDirectory string) (string, error) {
+	_, err := os.Stat(filepath.Join(driverDirectory, "package", "cli.js"))
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(filepath.Join(driverDirectory, "package", "package.json"))
+	if err != nil {
+		return "", err
+	}
+	var packageJSON struct {
+		Version string `json:"version"`
+	}
+	err = json.Unmarshal(b, &packageJSON)
+	if err != nil {
+		return "", fmt.Errorf("package.json: %w", err)
+	}
+	return packageJSON.Version, nil
+}
+
+// readDriverPlatform returns the platform of the driver unpacked in
+// driverDirectory, judging by the executable format and architecture of its
+// node binary.
+func readDriverPlatform(driverDirectory string) (string, error) {
+	nodePath := filepath.Join(driverDirectory, "node")
+	if elfFile, err := elf.Open(nodePath); err == nil {
+		defer elfFile.Close()
+		switch elfFile.Machine {
+		case elf.EM_X86_64:
+			return "linux", nil
+		case elf.EM_AARCH64:
+			return "linux-arm64", nil
 		}
+		return "", fmt.Errorf("node: unsupported architecture %s", elfFile.Machine)
+	}
+	if machoFile, err := macho.Open(nodePath); err == nil {
+		defer machoFile.Close()
+		switch machoFile.Cpu {
+		case macho.CpuAmd64:
+			return "mac", nil
+		case macho.CpuArm64:
+			return "mac-arm64", nil
+		}
+		return "", fmt.Errorf("node: unsupported architecture %s", machoFile.Cpu)
+	}
+	if peFile, err := pe.Open(filepath.Join(driverDirectory, "node.exe")); err == nil {
+		defer peFile.Close()
+		if peFile.Machine == pe.IMAGE_FILE_MACHINE_AMD64 {
+			return "win32_x64", nil
+		}
+		return "", fmt.Errorf("node.exe: unsupported architecture %#x", peFile.Machine)
+	}
+	return "", fmt.Errorf("no node executable found in the driver")
+}
+
+// RollbackDriver restores the driver that was replaced by the last install.
+// Rolling back twice restores the newer driver again. The driver must not be
+// running.
+func (backend *Backend) RollbackDriver() error {
+	if backend.Playwright != nil {
+		return fmt.Errorf("the driver is running, restart the app before rolling back")
 	}
-	writeResponse(w, "success", fmt.Sprintf("unzipped %s", filePath))
+	return rollbackDirectory(backend.PlaywrightRunOptions.DriverDirectory)
 }
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"changeme/stacktrace"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// BrowserLauncher is a Chromium-based browser installed on the machine that
+// can be launched with a remote debugging port.
+type BrowserLauncher struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	ExecutablePath string `json:"executablePath"`
+}
+
+// browserCandidates is the list of browsers we know how to find, in order of
+// preference.
+var browserCandidates = []struct {
+	ID   string
+	Name string
+	// WindowsPaths are relative to %ProgramFiles%, %ProgramFiles(x86)% and
+	// %LOCALAPPDATA%.
+	WindowsPaths []string
+	// WindowsAppPath is the executable name registered under the App Paths
+	// registry key.
+	WindowsAppPath string
+	// DarwinApp is the name of the .app bundle in /Applications or
+	// ~/Applications.
+	DarwinApp string
+	// LinuxNames are executable names looked up in the PATH.
+	LinuxNames []string
+}{
+	{
+		ID:             "chrome",
+		Name:           "Google Chrome",
+		WindowsPaths:   []string{`Google\Chrome\Application\chrome.exe`},
+		WindowsAppPath: "chrome.exe",
+		DarwinApp:      "Google Chrome",
+		LinuxNames:     []string{"google-chrome", "google-chrome-stable"},
+	},
+	{
+		ID:           "chrome-beta",
+		Name:         "Google Chrome Beta",
+		WindowsPaths: []string{`Google\Chrome Beta\Application\chrome.exe`},
+		DarwinApp:    "Google Chrome Beta",
+		LinuxNames:   []string{"google-chrome-beta"},
+	},
+	{
+		ID:           "chrome-canary",
+		Name:         "Google Chrome Canary",
+		WindowsPaths: []string{`Google\Chrome SxS\Application\chrome.exe`},
+		DarwinApp:    "Google Chrome Canary",
+		LinuxNames:   []string{"google-chrome-canary", "google-chrome-unstable"},
+	},
+	{
+		ID:             "edge",
+		Name:           "Microsoft Edge",
+		WindowsPaths:   []string{`Microsoft\Edge\Application\msedge.exe`},
+		WindowsAppPath: "msedge.exe",
+		DarwinApp:      "Microsoft Edge",
+		LinuxNames:     []string{"microsoft-edge", "microsoft-edge-stable"},
+	},
+	{
+		ID:             "brave",
+		Name:           "Brave",
+		WindowsPaths:   []string{`BraveSoftware\Brave-Browser\Application\brave.exe`},
+		WindowsAppPath: "brave.exe",
+		DarwinApp:      "Brave Browser",
+		LinuxNames:     []string{"brave-browser", "brave"},
+	},
+	{
+		ID:           "chromium",
+		Name:         "Chromium",
+		WindowsPaths: []string{`Chromium\Application\chrome.exe`},
+		DarwinApp:    "Chromium",
+		LinuxNames:   []string{"chromium", "chromium-browser"},
+	},
+}
+
+// DiscoverBrowserLaunchers probes the registry, standard install paths and
+// the PATH for Chromium-based browsers.
+func DiscoverBrowserLaunchers() []BrowserLauncher {
+	var browserLaunchers []BrowserLauncher
+	for _, candidate := range browserCandidates {
+		var executablePath string
+		switch runtime.GOOS {
+		case "windows":
+			if candidate.WindowsAppPath != "" {
+				executablePath = lookupWindowsAppPath(candidate.WindowsAppPath)
+			}
+			if executablePath != "" {
+				break
+			}
+			for _, root := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)"), os.Getenv("LOCALAPPDATA")} {
+				if root == "" {
+					continue
+				}
+				for _, windowsPath := range candidate.WindowsPaths {
+					if isExecutableFile(filepath.Join(root, windowsPath)) {
+						executablePath = filepath.Join(root, windowsPath)
+						break
+					}
+				}
+				if executablePath != "" {
+					break
+				}
+			}
+		case "darwin":
+			roots := []string{"/Applications"}
+			if userHomeDir, err := os.UserHomeDir(); err == nil {
+				roots = append(roots, filepath.Join(userHomeDir, "Applications"))
+			}
+			for _, root := range roots {
+				filePath := filepath.Join(root, candidate.DarwinApp+".app", "Contents", "MacOS", candidate.DarwinApp)
+				if isExecutableFile(filePath) {
+					executablePath = filePath
+					break
+				}
+			}
+		default:
+			for _, name := range candidate.LinuxNames {
+				filePath, err := exec.LookPath(name)
+				if err == nil {
+					executablePath = filePath
+					break
+				}
+			}
+		}
+		if executablePath == "" {
+			continue
+		}
+		browserLaunchers = append(browserLaunchers, BrowserLauncher{
+			ID:             candidate.ID,
+			Name:           candidate.Name,
+			ExecutablePath: executablePath,
+		})
+	}
+	if executablePath := findPlaywrightChromium(); executablePath != "" {
+		browserLaunchers = append(browserLaunchers, BrowserLauncher{
+			ID:             "playwright-chromium",
+			Name:           "Chromium (Playwright)",
+			ExecutablePath: executablePath,
+		})
+	}
+	return browserLaunchers
+}
+
+// lookupWindowsAppPath returns the executable path registered for name under
+// the App Paths registry key, checking the per-user key before the
+// machine-wide key. It returns an empty string if none is found.
+func lookupWindowsAppPath(name string) string {
+	for _, hive := range []string{"HKCU", "HKLM"} {
+		key := hive + `\SOFTWARE\Microsoft\Windows\CurrentVersion\App Paths\` + name
+		output, err := exec.Command("reg.exe", "query", key, "/ve").Output()
+		if err != nil {
+			continue
+		}
+		// The output looks like:
+		//
+		//   HKEY_CURRENT_USER\SOFTWARE\...\chrome.exe
+		//       (Default)    REG_SZ    C:\Users\...\chrome.exe
+		for _, line := range bytes.Split(output, []byte("\n")) {
+			_, value, ok := bytes.Cut(line, []byte("REG_SZ"))
+			if !ok {
+				continue
+			}
+			filePath := strings.Trim(string(bytes.TrimSpace(value)), `"`)
+			if isExecutableFile(filePath) {
+				return filePath
+			}
+		}
+	}
+	return ""
+}
+
+// playwrightBrowsersDirectory returns the directory Playwright installs its
+// browsers into: PLAYWRIGHT_BROWSERS_PATH, or the shared ms-playwright cache.
+func playwrightBrowsersDirectory() (string, error) {
+	if browsersDirectory := os.Getenv("PLAYWRIGHT_BROWSERS_PATH"); browsersDirectory != "" {
+		return browsersDirectory, nil
+	}
+	return defaultPlaywrightBrowsersDirectory()
+}
+
+// defaultPlaywrightBrowsersDirectory returns the shared ms-playwright cache,
+// where Playwright installs its browsers unless PLAYWRIGHT_BROWSERS_PATH is
+// set.
+func defaultPlaywrightBrowsersDirectory() (string, error) {
+	var cacheDirectory string
+	switch runtime.GOOS {
+	case "windows":
+		cacheDirectory = os.Getenv("LOCALAPPDATA")
+	case "darwin":
+		userHomeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cacheDirectory = filepath.Join(userHomeDir, "Library", "Caches")
+	default:
+		cacheDirectory = os.Getenv("XDG_CACHE_HOME")
+		if cacheDirectory == "" {
+			userHomeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			cacheDirectory = filepath.Join(userHomeDir, ".cache")
+		}
+	}
+	return filepath.Join(cacheDirectory, "ms-playwright"), nil
+}
+
+// findPlaywrightChromium returns the path to the latest Chromium managed by
+// Playwright, looking both in the app's browsers directory
+// (PLAYWRIGHT_BROWSERS_PATH) and in the shared ms-playwright cache that other
+// Playwright installs use. It returns an empty string if none is found.
+func findPlaywrightChromium() string {
+	var browsersDirectories []string
+	if browsersDirectory := os.Getenv("PLAYWRIGHT_BROWSERS_PATH"); browsersDirectory != "" {
+		browsersDirectories = append(browsersDirectories, browsersDirectory)
+	}
+	if browsersDirectory, err := defaultPlaywrightBrowsersDirectory(); err == nil {
+		browsersDirectories = append(browsersDirectories, browsersDirectory)
+	}
+	var patterns []string
+	switch runtime.GOOS {
+	case "windows":
+		patterns = []string{`chromium-*\chrome-win64\chrome.exe`, `chromium-*\chrome-win\chrome.exe`}
+	case "darwin":
+		patterns = []string{
+			"chromium-*/chrome-mac-arm64/Chromium.app/Contents/MacOS/Chromium",
+			"chromium-*/chrome-mac-x64/Chromium.app/Contents/MacOS/Chromium",
+			"chromium-*/chrome-mac/Chromium.app/Contents/MacOS/Chromium",
+		}
+	default:
+		patterns = []string{"chromium-*/chrome-linux64/chrome", "chromium-*/chrome-linux/chrome"}
+	}
+	// Pick the latest Chromium revision, preferring the app's browsers
+	// directory if both have it.
+	executablePath := ""
+	latestRevision := -1
+	for _, browsersDirectory := range browsersDirectories {
+		for _, pattern := range patterns {
+			matches, _ := filepath.Glob(filepath.Join(browsersDirectory, pattern))
+			for _, match := range matches {
+				relativePath, _ := filepath.Rel(browsersDirectory, match)
+				buildName, _, _ := strings.Cut(filepath.ToSlash(relativePath), "/")
+				revision, err := strconv.Atoi(strings.TrimPrefix(buildName, "chromium-"))
+				if err != nil || revision <= latestRevision || !isExecutableFile(match) {
+					continue
+				}
+				executablePath, latestRevision = match, revision
+			}
+		}
+	}
+	return executablePath
+}
+
+func isExecutableFile(filePath string) bool {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	if fileInfo.IsDir() {
+		return false
+	}
+	if runtime.GOOS != "windows" && fileInfo.Mode().Perm()&0111 == 0 {
+		return false
+	}
+	return true
+}
+
+// ListBrowserLaunchers returns the browsers available on the machine.
+func (backend *Backend) ListBrowserLaunchers() []BrowserLauncher {
+	return DiscoverBrowserLaunchers()
+}
+
+// SelectBrowserLauncher remembers the browser to launch in OpenBrowser.
+func (backend *Backend) SelectBrowserLauncher(id string) error {
+	for _, browserLauncher := range DiscoverBrowserLaunchers() {
+		if browserLauncher.ID != id {
+			continue
+		}
+		b, err := json.MarshalIndent(browserLauncher, "", "  ")
+		if err != nil {
+			return stacktrace.New(err)
+		}
+		err = os.WriteFile(backend.BrowserLauncherFile, b, 0644)
+		if err != nil {
+			return stacktrace.New(err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no such browser: %s", id)
+}
+
+// SelectedBrowserLauncher returns the browser that OpenBrowser will launch.
+// If the user has not picked one, it is the first browser found.
+func (backend *Backend) SelectedBrowserLauncher() (BrowserLauncher, error) {
+	var selected BrowserLauncher
+	b, err := os.ReadFile(backend.BrowserLauncherFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return BrowserLauncher{}, stacktrace.New(err)
+		}
+	} else {
+		err = json.Unmarshal(b, &selected)
+		if err != nil {
+			return BrowserLauncher{}, fmt.Errorf("%s: %w", backend.BrowserLauncherFile, err)
+		}
+	}
+	browserLaunchers := DiscoverBrowserLaunchers()
+	if selected.ID == "" {
+		if len(browserLaunchers) == 0 {
+			return BrowserLauncher{}, fmt.Errorf("could not find Google Chrome, Microsoft Edge, Brave or Chromium on this machine")
+		}
+		return browserLaunchers[0], nil
+	}
+	for _, browserLauncher := range browserLaunchers {
+		if browserLauncher.ID == selected.ID {
+			return browserLauncher, nil
+		}
+	}
+	// The browser may have been installed somewhere we don't know how to
+	// discover, so fall back to the executable path we remembered.
+	if isExecutableFile(selected.ExecutablePath) {
+		return selected, nil
+	}
+	return BrowserLauncher{}, fmt.Errorf("%s is no longer installed, please select another browser", selected.Name)
+}
@@ -1,15 +1,14 @@
 package main
 
 import (
-	"changeme/stacktrace"
 	"context"
 	"embed"
 	_ "embed"
 	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/playwright-community/playwright-go"
@@ -20,40 +19,13 @@ import (
 var assets embed.FS
 
 func main() {
-	var playwrightDriver *playwright.PlaywrightDriver
-	var playwrightRunOptions *playwright.RunOptions
-	var chromeProfileDirectory string
-	startupErr := func() error {
-		userHomeDir, err := os.UserHomeDir()
-		if err != nil {
-			return stacktrace.New(err)
-		}
-		var driverDirectory string
-		if s := os.Getenv("PLAYWRIGHT_DRIVER_PATH"); s != "" {
-			driverDirectory = s
-		} else {
-			driverDirectory = filepath.Join(userHomeDir, "browserautomate", "playwrightdriver")
-		}
-		err = os.MkdirAll(driverDirectory, 0777)
-		if err != nil {
-			return stacktrace.New(err)
-		}
-		chromeProfileDirectory = filepath.Join(userHomeDir, "browserautomate", "chromeprofile")
-		err = os.MkdirAll(chromeProfileDirectory, 0777)
-		if err != nil {
-			return stacktrace.New(err)
-		}
-		playwrightRunOptions = &playwright.RunOptions{
-			DriverDirectory:     driverDirectory,
-			SkipInstallBrowsers: true,
-			Verbose:             true,
-		}
-		playwrightDriver, err = playwright.NewDriver(playwrightRunOptions)
-		if err != nil {
-			return stacktrace.New(err)
-		}
-		return nil
-	}()
+	// Read the environment before the backend sets any of the variables.
+	settingsEnv := environmentSettings()
+	settingsFlags, err := parseSettingsFlags(os.Args[1:])
+	if err != nil {
+		// Start anyway, without the flags.
+		slog.Error("parsing command line flags: " + err.Error())
+	}
 	app := application.New(application.Options{
 		Name:        "ba2",
 		Description: "A demo of using raw HTML & CSS",
@@ -65,47 +37,43 @@ func main() {
 		},
 	})
 	backend := &Backend{
-		App:                    app,
-		PlaywrightDriver:       playwrightDriver,
-		PlaywrightRunOptions:   playwrightRunOptions,
-		ChromeProfileDirectory: chromeProfileDirectory,
-		Windows:                make(map[string]*application.WebviewWindow),
+		App:             app,
+		Sessions:        make(map[string]*BrowserSession),
+		OpeningProfiles: make(map[string]bool),
+		Windows:         make(map[string]*application.WebviewWindow),
+		Pages:           make(map[int64]playwright.Page),
+		TabDetails:      make(map[int64]*TabDetail),
+		FlowRuns:        make(map[string]*FlowRun),
+		Processes:       make(map[string]*Process),
+		Startup:         StartupStatus{State: StartupStateStarting},
+		SettingsEnv:     settingsEnv,
+		SettingsFlags:   settingsFlags,
 	}
 	defer backend.Close()
 	app.RegisterService(application.NewServiceWithOptions(backend, application.ServiceOptions{
 		Route: "/backend",
 	}))
+	backend.start()
+	httpAddress := backend.settings().HTTPAddress
+	if httpAddress == "" {
+		// The settings could not be loaded.
+		httpAddress = "localhost:9246"
+	}
 	go func() {
-		http.ListenAndServe("localhost:9246", backend)
+		http.ListenAndServe(httpAddress, backend.controlAPI(httpAddress))
 	}()
-	window := app.Window.NewWithOptions(application.WebviewWindowOptions{
-		Title: "Browser Automate",
-		Mac: application.MacWindow{
-			InvisibleTitleBarHeight: 50,
-			Backdrop:                application.MacBackdropTranslucent,
-			TitleBar:                application.MacTitleBarHiddenInset,
-		},
-		BackgroundColour: application.NewRGB(27, 38, 54),
-		URL:              "/index.html?foo=bar&foo=baz",
-	})
-	backend.Mutex.Lock()
-	backend.Windows["index"] = window
-	backend.Mutex.Unlock()
 	go func() {
 		for {
 			now := time.Now().Format(time.RFC1123)
-			app.Event.Emit("time", now)
+			backend.emitEvent(&application.CustomEvent{
+				Name: "time",
+				Data: now,
+			})
 			time.Sleep(time.Second)
 		}
 	}()
-	err := app.Run()
+	err = app.Run()
 	if err != nil && !errors.Is(err, context.Canceled) {
 		log.Fatal(err)
 	}
-	if startupErr != nil {
-		dialog := app.Dialog.Error()
-		dialog.SetTitle("Error")
-		dialog.SetMessage(startupErr.Error())
-		dialog.Show()
-	}
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// NetworkSettings configure how the backend reaches the internet. They apply
+// to every outbound HTTP client the backend creates and to the driver when it
+// downloads browsers. They are part of Settings.
+type NetworkSettings struct {
+	// ProxyURL is the proxy for both HTTP and HTTPS requests, e.g.
+	// "http://proxy.example.com:3128". If empty, HTTPS_PROXY, HTTP_PROXY and
+	// NO_PROXY from the environment are used.
+	ProxyURL string `json:"proxyURL"`
+
+	// ProxyUsername and ProxyPassword are sent to the proxy with basic
+	// authentication. They are stored in plain text, but the password is
+	// never sent to the windows or API clients, see GetSettings.
+	ProxyUsername string `json:"proxyUsername"`
+	ProxyPassword string `json:"proxyPassword"`
+
+	// NoProxy lists the hosts that bypass ProxyURL, in the same format as
+	// NO_PROXY (comma separated host names, domains and CIDR ranges).
+	NoProxy string `json:"noProxy"`
+
+	// CABundleFile is a PEM file of certificate authorities to trust in
+	// addition to the system ones, e.g. for a TLS-intercepting proxy.
+	CABundleFile string `json:"caBundleFile"`
+}
+
+// proxyURL returns the proxy URL with the credentials filled in, or nil if no
+// proxy is configured.
+func (settings NetworkSettings) proxyURL() (*url.URL, error) {
+	if settings.ProxyURL == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(settings.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", settings.ProxyURL, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", settings.ProxyURL)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", settings.ProxyURL)
+	}
+	if settings.ProxyUsername != "" {
+		proxyURL.User = url.UserPassword(settings.ProxyUsername, settings.ProxyPassword)
+	}
+	return proxyURL, nil
+}
+
+// Validate checks that the proxy URL can be parsed and that the CA bundle
+// contains at least one certificate.
+func (settings NetworkSettings) Validate() error {
+	_, err := settings.proxyURL()
+	if err != nil {
+		return err
+	}
+	_, err = settings.rootCAs()
+	return err
+}
+
+// rootCAs returns the system certificate pool with CABundleFile added, or nil
+// (meaning the system pool) if there is no CA bundle.
+func (settings NetworkSettings) rootCAs() (*x509.CertPool, error) {
+	if settings.CABundleFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(settings.CABundleFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s does not contain any PEM certificates", settings.CABundleFile)
+	}
+	return rootCAs, nil
+}
+
+// newHTTPClient returns an HTTP client that uses the settings' proxy and CA
+// bundle. The client has no overall timeout, callers are expected to use a
+// context.
+func (settings NetworkSettings) newHTTPClient() (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+	proxyURL, err := settings.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		proxyFunc := (&httpproxy.Config{
+			HTTPProxy:  proxyURL.String(),
+			HTTPSProxy: proxyURL.String(),
+			NoProxy:    settings.NoProxy,
+		}).ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+	rootCAs, err := settings.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// applyToCommand passes the settings on to a driver subprocess through the
+// environment variables Node and Playwright understand.
+func (settings NetworkSettings) applyToCommand(cmd *exec.Cmd) error {
+	proxyURL, err := settings.proxyURL()
+	if err != nil {
+		return err
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	if proxyURL != nil {
+		cmd.Env = append(cmd.Env,
+			"HTTPS_PROXY="+proxyURL.String(),
+			"HTTP_PROXY="+proxyURL.String(),
+		)
+		// An empty NoProxy keeps NO_PROXY from the environment.
+		if settings.NoProxy != "" {
+			cmd.Env = append(cmd.Env, "NO_PROXY="+settings.NoProxy)
+		}
+	}
+	if settings.CABundleFile != "" {
+		cmd.Env = append(cmd.Env, "NODE_EXTRA_CA_CERTS="+settings.CABundleFile)
+	}
+	return nil
+}
+
+// CheckNetworkSettings makes a request to the driver download host with the
+// given (not necessarily saved) network settings, so that they can be tried
+// out before saving them. As with UpdateSettings, an empty proxy password
+// means the saved one.
+func (backend *Backend) CheckNetworkSettings(ctx context.Context, settings NetworkSettings) error {
+	// Without a driver there is no archive name to ask for.
+	if !backend.ready() {
+		return fmt.Errorf("the backend failed to start, see the recovery window")
+	}
+	downloadMirrors := backend.settings().DownloadMirrors
+	if len(downloadMirrors) == 0 {
+		return fmt.Errorf("no download mirrors configured")
+	}
+	httpClient, err := settings.withSavedProxyPassword().newHTTPClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	origin := downloadMirrors[0]
+	req, err := http.NewRequestWithContext(ctx, "HEAD", origin+"/builds/driver/"+driverArchiveName(backend.PlaywrightDriver.Version, driverPlatform()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		return fmt.Errorf("HEAD %s: %s (check the proxy username and password)", req.URL, resp.Status)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HEAD %s: %s", req.URL, resp.Status)
+	}
+	return nil
+}
+
+// httpClient returns an HTTP client for outbound requests using the network
+// settings in effect.
+func (backend *Backend) httpClient() (*http.Client, error) {
+	return backend.settings().NetworkSettings.newHTTPClient()
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// Process reports the progress of a long-running operation to the status bar
+// of the window that started it, as ProcessUpdate events. A process is
+// started with Backend.beginProcess and must end with Finish or Fail, after
+// which further updates are ignored.
+//
+// While it is running a process is listed in Backend.Processes and can be
+// cancelled with CancelProcess, which cancels its Context.
+type Process struct {
+	backend    *Backend
+	processID  string
+	windowName string
+	ctx        context.Context
+	cancel     context.CancelFunc
+
+	mutex         sync.Mutex
+	message       string
+	progressValue int
+	progressMax   int
+	done          bool
+}
+
+// beginProcess starts a process owned by windowName whose Context is derived
+// from ctx. If progressMax is zero the progress is reported as indeterminate
+// until SetProgress is called. If windowName is empty the updates are
+// broadcast to all windows.
+func (backend *Backend) beginProcess(ctx context.Context, windowName string, message string, progressMax int) *Process {
+	process := &Process{
+		backend:     backend,
+		processID:   "process-" + strconv.FormatInt(backend.Sequence.Add(1), 10),
+		windowName:  windowName,
+		message:     message,
+		progressMax: progressMax,
+	}
+	process.ctx, process.cancel = context.WithCancel(ctx)
+	backend.Mutex.Lock()
+	backend.Processes[process.processID] = process
+	backend.Mutex.Unlock()
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	process.emit()
+	return process
+}
+
+// windowName returns the name of the window that called a service method, or
+// an empty string if the call did not come from a window.
+func windowName(ctx context.Context) string {
+	window, ok := ctx.Value(application.WindowKey).(application.Window)
+	if !ok {
+		return ""
+	}
+	return window.Name()
+}
+
+// ProcessID returns the ID the process is reported under.
+func (process *Process) ProcessID() string {
+	return process.processID
+}
+
+// Context returns the process's context, which is cancelled when the process
+// is cancelled or ends.
+func (process *Process) Context() context.Context {
+	return process.ctx
+}
+
+// Step advances the progress by one and updates the message.
+func (process *Process) Step(message string) {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	if process.done {
+		return
+	}
+	process.message = message
+	process.progressValue++
+	process.emit()
+}
+
+// SetMessage updates the message without changing the progress.
+func (process *Process) SetMessage(message string) {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	if process.done {
+		return
+	}
+	process.message = message
+	process.emit()
+}
+
+// SetProgress updates the message and sets the progress to value out of max,
+// e.g. bytes downloaded out of the total size.
+func (process *Process) SetProgress(message string, value int, max int) {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	if process.done {
+		return
+	}
+	process.message = message
+	process.progressValue = value
+	process.progressMax = max
+	process.emit()
+}
+
+// Finish marks the process as done, which removes it from the status bar.
+func (process *Process) Finish(message string) {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	if process.done {
+		return
+	}
+	process.done = true
+	if message != "" {
+		process.message = message
+	}
+	process.emit()
+	process.end()
+}
+
+// Fail marks the process as done with an error.
+func (process *Process) Fail(err error) {
+	process.mutex.Lock()
+	defer process.mutex.Unlock()
+	if process.done {
+		return
+	}
+	process.done = true
+	if errors.Is(err, context.Canceled) {
+		process.message = "cancelled: " + process.message
+	} else {
+		process.message = "error: " + err.Error()
+	}
+	process.emit()
+	process.end()
+}
+
+// end removes the process from Backend.Processes and releases its context.
+// Must be called with process.mutex held.
+func (process *Process) end() {
+	process.backend.Mutex.Lock()
+	delete(process.backend.Processes, process.processID)
+	process.backend.Mutex.Unlock()
+	process.cancel()
+}
+
+// CancelProcess cancels a running process. The process reports itself as
+// cancelled once the operation has stopped.
+func (backend *Backend) CancelProcess(processID string) error {
+	backend.Mutex.Lock()
+	process, ok := backend.Processes[processID]
+	backend.Mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("no such process: %s", processID)
+	}
+	process.cancel()
+	return nil
+}
+
+// ListProcesses returns the state of the running processes, oldest first.
+func (backend *Backend) ListProcesses() []ProcessUpdate {
+	backend.Mutex.Lock()
+	processes := make([]*Process, 0, len(backend.Processes))
+	for _, process := range backend.Processes {
+		processes = append(processes, process)
+	}
+	backend.Mutex.Unlock()
+	// "process-9" sorts before "process-10".
+	slices.SortFunc(processes, func(a, b *Process) int {
+		return cmp.Or(cmp.Compare(len(a.processID), len(b.processID)), strings.Compare(a.processID, b.processID))
+	})
+	processUpdates := make([]ProcessUpdate, 0, len(processes))
+	for _, process := range processes {
+		process.mutex.Lock()
+		processUpdates = append(processUpdates, ProcessUpdate{
+			ProcessID:     process.processID,
+			Message:       process.message,
+			ProgressValue: process.progressValue,
+			ProgressMax:   process.progressMax,
+			Timestamp:     time.Now().Unix(),
+		})
+		process.mutex.Unlock()
+	}
+	return processUpdates
+}
+
+// cancelprocess serves POST /cancelprocess/ (form field: processID).
+func (backend *Backend) cancelprocess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	processID := r.Form.Get("processID")
+	if processID == "" {
+		http.Error(w, "MissingProcessID", http.StatusBadRequest)
+		return
+	}
+	err := backend.CancelProcess(processID)
+	if err != nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// emit sends the current state of the process. The status bar treats a
+// process whose progressValue has reached progressMax as finished, so until
+// the process is done the reported progressValue is kept below progressMax.
+// Must be called with process.mutex held.
+func (process *Process) emit() {
+	processUpdate := ProcessUpdate{
+		ProcessID:     process.processID,
+		Message:       process.message,
+		ProgressValue: process.progressValue,
+		ProgressMax:   process.progressMax,
+		Timestamp:     time.Now().Unix(),
+	}
+	if processUpdate.ProgressMax <= 0 {
+		processUpdate.ProgressMax = 1
+	}
+	if process.done {
+		processUpdate.ProgressValue = processUpdate.ProgressMax
+	} else {
+		processUpdate.ProgressValue = min(processUpdate.ProgressValue, processUpdate.ProgressMax-1)
+	}
+	process.backend.emitWindowEvent(&application.CustomEvent{
+		Sender: process.windowName,
+		Name:   "ProcessUpdate",
+		Data:   processUpdate,
+	})
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"changeme/stacktrace"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultProfileName is the profile used when no profile name is given.
+const DefaultProfileName = "default"
+
+// Profile is a Chrome user data directory managed by the backend. Each
+// profile has its own logged-in identities and its own remote debugging port
+// so that several browsers can be attached at the same time.
+type Profile struct {
+	Name      string `json:"name"`
+	Directory string `json:"directory"`
+	CDPPort   int    `json:"cdpPort"`
+	Connected bool   `json:"connected"`
+}
+
+// profileDirectory validates the profile name and returns its user data
+// directory.
+func (backend *Backend) profileDirectory(name string) (string, error) {
+	if name == "" {
+		name = DefaultProfileName
+	}
+	if name == "." || name == ".." || len(name) > 64 {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	for _, char := range name {
+		if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') || char == '-' || char == '_' || char == '.' || char == ' ' {
+			continue
+		}
+		return "", fmt.Errorf("invalid profile name %q: only letters, numbers, spaces, '-', '_' and '.' are allowed", name)
+	}
+	return filepath.Join(backend.ProfilesDirectory, name), nil
+}
+
+// reserveProfiles marks the profiles as busy for as long as an operation on
+// their directories runs, the same way openBrowser does while it opens a
+// profile, and returns a function that releases them. It fails if any of the
+// profiles is being opened or changed, has a session (possibly waiting to
+// reconnect) or a browser is running with it.
+func (backend *Backend) reserveProfiles(names ...string) (func(), error) {
+	release := func() {
+		backend.Mutex.Lock()
+		for _, name := range names {
+			delete(backend.OpeningProfiles, name)
+		}
+		backend.Mutex.Unlock()
+	}
+	backend.Mutex.Lock()
+	for _, name := range names {
+		_, hasSession := backend.Sessions[name]
+		if backend.OpeningProfiles[name] || hasSession {
+			backend.Mutex.Unlock()
+			return nil, fmt.Errorf("profile %q is in use, close its browser first", name)
+		}
+	}
+	for _, name := range names {
+		backend.OpeningProfiles[name] = true
+	}
+	backend.Mutex.Unlock()
+	// Checking for a browser we are not attached to takes a request, it
+	// happens after reserving so that nothing can open the profile
+	// meanwhile.
+	for _, name := range names {
+		if backend.profileBrowserRunning(name) {
+			release()
+			return nil, fmt.Errorf("profile %q is in use, close its browser first", name)
+		}
+	}
+	return release, nil
+}
+
+// profileBrowserRunning reports whether a browser is running with the
+// profile, whether or not we are attached to it.
+func (backend *Backend) profileBrowserRunning(name string) bool {
+	backend.Mutex.Lock()
+	session := backend.Sessions[name]
+	backend.Mutex.Unlock()
+	if session.IsConnected() {
+		return true
+	}
+	profileDirectory := filepath.Join(backend.ProfilesDirectory, name)
+	port, _, err := readDevToolsActivePort(profileDirectory)
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return verifyCDP(ctx, port, profileDirectory) == nil
+}
+
+// ListProfiles returns the profiles in the profiles directory.
+func (backend *Backend) ListProfiles() ([]Profile, error) {
+	dirEntries, err := os.ReadDir(backend.ProfilesDirectory)
+	if err != nil {
+		return nil, stacktrace.New(err)
+	}
+	profiles := make([]Profile, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		profileDirectory := filepath.Join(backend.ProfilesDirectory, dirEntry.Name())
+		config, err := readChromeProfileConfig(profileDirectory)
+		if err != nil {
+			return nil, err
+		}
+		backend.Mutex.Lock()
+		session := backend.Sessions[dirEntry.Name()]
+		backend.Mutex.Unlock()
+		profiles = append(profiles, Profile{
+			Name:      dirEntry.Name(),
+			Directory: profileDirectory,
+			CDPPort:   config.CDPPort,
+			Connected: session.IsConnected(),
+		})
+	}
+	return profiles, nil
+}
+
+// CreateProfile creates a new empty profile.
+func (backend *Backend) CreateProfile(name string) error {
+	profileDirectory, err := backend.profileDirectory(name)
+	if err != nil {
+		return err
+	}
+	err = os.Mkdir(profileDirectory, 0755)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("profile %q already exists", name)
+		}
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// RenameProfile renames a profile. The profile must not be in use.
+func (backend *Backend) RenameProfile(oldName, newName string) error {
+	oldDirectory, err := backend.profileDirectory(oldName)
+	if err != nil {
+		return err
+	}
+	newDirectory, err := backend.profileDirectory(newName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(oldDirectory); err != nil {
+		return fmt.Errorf("no such profile: %s", oldName)
+	}
+	if _, err := os.Stat(newDirectory); err == nil {
+		return fmt.Errorf("profile %q already exists", newName)
+	}
+	release, err := backend.reserveProfiles(oldName, newName)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = os.Rename(oldDirectory, newDirectory)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// DeleteProfile deletes a profile and everything in it. The profile must not
+// be in use.
+func (backend *Backend) DeleteProfile(name string) error {
+	profileDirectory, err := backend.profileDirectory(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(profileDirectory); err != nil {
+		return fmt.Errorf("no such profile: %s", name)
+	}
+	release, err := backend.reserveProfiles(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = os.RemoveAll(profileDirectory)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// CloneProfile copies an existing profile (including its logged-in sessions)
+// into a new profile. The clone always picks its CDP port automatically so
+// that it can run side by side with the original. The source profile must
+// not be in use.
+func (backend *Backend) CloneProfile(srcName, destName string) error {
+	srcDirectory, err := backend.profileDirectory(srcName)
+	if err != nil {
+		return err
+	}
+	destDirectory, err := backend.profileDirectory(destName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(srcDirectory); err != nil {
+		return fmt.Errorf("no such profile: %s", srcName)
+	}
+	if _, err := os.Stat(destDirectory); err == nil {
+		return fmt.Errorf("profile %q already exists", destName)
+	}
+	// Chrome rewrites its databases while it runs, a copy taken meanwhile
+	// can be inconsistent.
+	release, err := backend.reserveProfiles(srcName, destName)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = filepath.WalkDir(srcDirectory, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(srcDirectory, filePath)
+		if err != nil {
+			return err
+		}
+		destFilePath := filepath.Join(destDirectory, relativePath)
+		if dirEntry.IsDir() {
+			return os.MkdirAll(destFilePath, 0755)
+		}
+		// Skip the files that tie a profile to a running browser instance.
+		name := dirEntry.Name()
+		if name == "DevToolsActivePort" || name == "lockfile" || strings.HasPrefix(name, "Singleton") {
+			return nil
+		}
+		if !dirEntry.Type().IsRegular() {
+			return nil
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		srcFile, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer srcFile.Close()
+		destFile, err := os.OpenFile(destFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileInfo.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(destFile, srcFile)
+		if err != nil {
+			destFile.Close()
+			return err
+		}
+		return destFile.Close()
+	})
+	if err != nil {
+		os.RemoveAll(destDirectory)
+		return fmt.Errorf("cloning profile %q: %w", srcName, err)
+	}
+	config, err := readChromeProfileConfig(destDirectory)
+	if err != nil {
+		return err
+	}
+	if config.CDPPort != 0 {
+		config.CDPPort = 0
+		err = writeChromeProfileConfig(destDirectory, config)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
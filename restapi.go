@@ -0,0 +1,295 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// openAPIDocument describes the /v1/ API and the /events stream.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// restAPIError is the body of an error response from the /v1/ API.
+type restAPIError struct {
+	Error string `json:"error"`
+}
+
+// v1 serves version 1 of the REST API, which mirrors the service methods so
+// that the app can be driven without the GUI. Request and response bodies are
+// JSON, see openapi.json for the details.
+//
+//	GET    /v1/browsers/                 lists the open browser sessions
+//	POST   /v1/browsers/                 opens a browser (OpenBrowserOptions)
+//	DELETE /v1/browsers/<profile>/       closes a profile's browser
+//	GET    /v1/tabs/                     lists the tabs
+//	POST   /v1/tabs/                     opens a tab ({profileName, url})
+//	GET    /v1/tabs/<tabID>/             returns a tab's details
+//	DELETE /v1/tabs/<tabID>/             closes a tab
+//	POST   /v1/tabs/<tabID>/activate/    brings a tab to the front
+//	GET    /v1/flows/                    lists the flows
+//	GET    /v1/flows/<name>/             returns a flow definition
+//	POST   /v1/flows/<name>/runs/        starts a flow run ({tabID})
+//	GET    /v1/flowruns/                 lists the flow runs
+//	GET    /v1/flowruns/<runID>/         returns a flow run
+//	GET    /v1/processes/                lists the running processes
+//	DELETE /v1/processes/<processID>/    cancels a process
+//	GET    /v1/settings/                 returns the settings
+//	PUT    /v1/settings/                 saves the settings (Settings)
+func (backend *Backend) v1(w http.ResponseWriter, r *http.Request, pathTail string) {
+	writeResponse := func(w http.ResponseWriter, r *http.Request, statusCode int, response any) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(statusCode)
+		if r.Method == "HEAD" {
+			return
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(response)
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+	writeError := func(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+		writeResponse(w, r, statusCode, restAPIError{Error: err.Error()})
+	}
+	readRequest := func(r *http.Request, request any) error {
+		decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(request)
+		if err != nil {
+			return fmt.Errorf("invalid request body: %w", err)
+		}
+		return nil
+	}
+	errMethodNotAllowed := errors.New("method not allowed")
+	errNotFound := errors.New("not found")
+	resource, tail, _ := strings.Cut(pathTail, "/")
+	id, action, _ := strings.Cut(tail, "/")
+	switch resource {
+	case "browsers":
+		if id != "" {
+			switch {
+			case action != "":
+				writeError(w, r, http.StatusNotFound, errNotFound)
+			case r.Method == "DELETE":
+				err := backend.CloseBrowser(id)
+				if err != nil {
+					writeError(w, r, http.StatusNotFound, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+			}
+			return
+		}
+		switch r.Method {
+		case "GET", "HEAD":
+			writeResponse(w, r, http.StatusOK, backend.ListBrowserSessions())
+		case "POST":
+			var options OpenBrowserOptions
+			err := readRequest(r, &options)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+			err = backend.StartPlaywright()
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			// Progress is broadcast to the status bar of every window.
+			err = backend.openBrowser(r.Context(), "", options)
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, backend.ListBrowserSessions())
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		}
+	case "tabs":
+		if id == "" {
+			switch r.Method {
+			case "GET", "HEAD":
+				writeResponse(w, r, http.StatusOK, backend.ListTabs())
+			case "POST":
+				var request struct {
+					ProfileName string `json:"profileName"`
+					URL         string `json:"url"`
+				}
+				err := readRequest(r, &request)
+				if err != nil {
+					writeError(w, r, http.StatusBadRequest, err)
+					return
+				}
+				tab, err := backend.NewTab(request.ProfileName, request.URL)
+				if err != nil {
+					writeError(w, r, http.StatusBadRequest, err)
+					return
+				}
+				writeResponse(w, r, http.StatusCreated, tab)
+			default:
+				writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+			}
+			return
+		}
+		tabID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			writeError(w, r, http.StatusNotFound, errNotFound)
+			return
+		}
+		switch {
+		case action == "" && (r.Method == "GET" || r.Method == "HEAD"):
+			tabDetail, err := backend.TabDetail(tabID)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, tabDetail)
+		case action == "" && r.Method == "DELETE":
+			err := backend.CloseTab(tabID)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case action == "activate" && r.Method == "POST":
+			err := backend.ActivateTab(tabID)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case action == "" || action == "activate":
+			writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		default:
+			writeError(w, r, http.StatusNotFound, errNotFound)
+		}
+	case "flows":
+		switch {
+		case id == "" && (r.Method == "GET" || r.Method == "HEAD"):
+			flows, err := backend.ListFlows()
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, flows)
+		case id != "" && action == "" && (r.Method == "GET" || r.Method == "HEAD"):
+			flow, err := backend.Flow(id)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, flow)
+		case id != "" && action == "runs" && r.Method == "POST":
+			var request struct {
+				TabID int64 `json:"tabID"`
+			}
+			err := readRequest(r, &request)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+			flowRun, err := backend.startFlow("", id, request.TabID)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+			writeResponse(w, r, http.StatusAccepted, flowRun)
+		case action == "" || action == "runs":
+			writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		default:
+			writeError(w, r, http.StatusNotFound, errNotFound)
+		}
+	case "flowruns":
+		switch {
+		case action != "":
+			writeError(w, r, http.StatusNotFound, errNotFound)
+		case r.Method != "GET" && r.Method != "HEAD":
+			writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		case id == "":
+			writeResponse(w, r, http.StatusOK, backend.ListFlowRuns())
+		default:
+			flowRun, err := backend.FlowRun(id)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, flowRun)
+		}
+	case "processes":
+		switch {
+		case action != "":
+			writeError(w, r, http.StatusNotFound, errNotFound)
+		case id == "" && (r.Method == "GET" || r.Method == "HEAD"):
+			writeResponse(w, r, http.StatusOK, backend.ListProcesses())
+		case id != "" && r.Method == "DELETE":
+			err := backend.CancelProcess(id)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		}
+	case "settings":
+		if id != "" {
+			writeError(w, r, http.StatusNotFound, errNotFound)
+			return
+		}
+		switch r.Method {
+		case "GET", "HEAD":
+			settingsInfo, err := backend.GetSettings()
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, settingsInfo)
+		case "PUT":
+			var settings Settings
+			err := readRequest(r, &settings)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+			err = backend.UpdateSettings(settings)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+			settingsInfo, err := backend.GetSettings()
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			writeResponse(w, r, http.StatusOK, settingsInfo)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		}
+	default:
+		writeError(w, r, http.StatusNotFound, errNotFound)
+	}
+}
+
+// openapi serves GET /openapi.json.
+func (backend *Backend) openapi(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if r.Method == "HEAD" {
+		return
+	}
+	w.Write(openAPIDocument)
+}
@@ -7,6 +7,11 @@ import (
 )
 
 func (backend *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The OpenAPI document keeps the name tools look for.
+	if r.URL.Path == "/openapi.json" {
+		backend.openapi(w, r)
+		return
+	}
 	// Redirect unclean paths to the clean path equivalent.
 	urlPath := path.Clean(r.URL.Path)
 	if urlPath != "/" {
@@ -25,6 +30,12 @@ func (backend *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
+	// Until the backend is initialised (see RetryStartup) there is no
+	// driver to report on.
+	if !backend.ready() {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
 	pathHead, pathTail, _ := strings.Cut(strings.Trim(urlPath, "/"), "/")
 	switch pathHead {
 	case "driver":
@@ -41,6 +52,43 @@ func (backend *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		backend.installdriver(w, r)
 		return
+	case "drivers":
+		backend.drivers(w, r, pathTail)
+		return
+	case "importdriver":
+		if pathTail != "" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		backend.importdriver(w, r)
+		return
+	case "exportdriver":
+		if pathTail != "" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		backend.exportdriver(w, r)
+		return
+	case "cancelprocess":
+		if pathTail != "" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		backend.cancelprocess(w, r)
+		return
+	case "flow":
+		backend.flow(w, r, pathTail)
+		return
+	case "events":
+		if pathTail != "" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		backend.events(w, r)
+		return
+	case "v1":
+		backend.v1(w, r, pathTail)
+		return
 	default:
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	// LaunchModeCDP launches (or reuses) a user-visible browser with a remote
+	// debugging port and attaches to it over the Chrome DevTools Protocol.
+	LaunchModeCDP = "cdp"
+
+	// LaunchModePersistent launches Playwright's own Chromium, Firefox or
+	// WebKit build with BrowserType.LaunchPersistentContext, optionally
+	// headless. Intended for unattended runs.
+	LaunchModePersistent = "persistent"
+)
+
+const (
+	BrowserChromium = "chromium"
+	BrowserFirefox  = "firefox"
+	BrowserWebKit   = "webkit"
+)
+
+// playwrightBrowserType returns the Playwright browser type for a browser
+// name.
+func (backend *Backend) playwrightBrowserType(browserName string) (playwright.BrowserType, error) {
+	switch browserName {
+	case BrowserChromium:
+		return backend.Playwright.Chromium, nil
+	case BrowserFirefox:
+		return backend.Playwright.Firefox, nil
+	case BrowserWebKit:
+		return backend.Playwright.WebKit, nil
+	default:
+		return nil, fmt.Errorf("invalid browser %q", browserName)
+	}
+}
+
+// BrowserSession is a browser opened for a profile.
+type BrowserSession struct {
+	ProfileName string
+	LaunchMode  string
+	BrowserName string
+	Headless    bool
+
+	// Browser is set for sessions attached over CDP.
+	Browser playwright.Browser
+
+	// BrowserContext is set for sessions launched with
+	// LaunchPersistentContext, which do not have a Browser.
+	BrowserContext playwright.BrowserContext
+
+	// PageIDs are the IDs of the session's pages in Backend.Pages. Guarded by
+	// Backend.Mutex.
+	PageIDs map[int64]struct{}
+
+	// disconnected is closed once the browser is disconnected.
+	disconnected     chan struct{}
+	disconnectedOnce sync.Once
+
+	// closing is set when we close the session ourselves, so that the
+	// supervisor does not try to reconnect.
+	closing atomic.Bool
+
+	// done is closed by Close, it interrupts the supervisor while it waits
+	// to reconnect.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newCDPBrowserSession(profileName string, browser playwright.Browser) *BrowserSession {
+	session := &BrowserSession{
+		ProfileName:  profileName,
+		LaunchMode:   LaunchModeCDP,
+		BrowserName:  BrowserChromium,
+		Browser:      browser,
+		PageIDs:      make(map[int64]struct{}),
+		disconnected: make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	browser.OnDisconnected(func(playwright.Browser) {
+		session.disconnectedOnce.Do(func() { close(session.disconnected) })
+	})
+	return session
+}
+
+func newPersistentBrowserSession(profileName, browserName string, headless bool, browserContext playwright.BrowserContext) *BrowserSession {
+	session := &BrowserSession{
+		ProfileName:    profileName,
+		LaunchMode:     LaunchModePersistent,
+		BrowserName:    browserName,
+		Headless:       headless,
+		BrowserContext: browserContext,
+		PageIDs:        make(map[int64]struct{}),
+		disconnected:   make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	browserContext.OnClose(func(playwright.BrowserContext) {
+		session.disconnectedOnce.Do(func() { close(session.disconnected) })
+	})
+	return session
+}
+
+// IsConnected reports whether the session's browser is still usable.
+func (session *BrowserSession) IsConnected() bool {
+	if session == nil {
+		return false
+	}
+	select {
+	case <-session.disconnected:
+		return false
+	default:
+	}
+	if session.Browser != nil {
+		return session.Browser.IsConnected()
+	}
+	return session.BrowserContext != nil
+}
+
+// Contexts returns the browser contexts of the session.
+func (session *BrowserSession) Contexts() []playwright.BrowserContext {
+	if !session.IsConnected() {
+		return nil
+	}
+	if session.Browser != nil {
+		return session.Browser.Contexts()
+	}
+	return []playwright.BrowserContext{session.BrowserContext}
+}
+
+// Close closes the session. For CDP sessions this only disconnects from the
+// browser, the browser itself is left running.
+func (session *BrowserSession) Close() error {
+	session.closing.Store(true)
+	session.doneOnce.Do(func() { close(session.done) })
+	if session.Browser != nil {
+		return session.Browser.Close()
+	}
+	if session.BrowserContext != nil {
+		return session.BrowserContext.Close()
+	}
+	return nil
+}
+
+func init() {
+	application.RegisterEvent[BrowserStatus]("BrowserStatus")
+}
+
+// BrowserStatus is emitted to all windows whenever a profile's browser
+// connects, disconnects or is being reconnected.
+type BrowserStatus struct {
+	ProfileName string `json:"profileName"`
+	Status      string `json:"status"` // connected|disconnected|reconnecting|failed
+	Message     string `json:"message"`
+	Attempt     int    `json:"attempt"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+func (backend *Backend) emitBrowserStatus(browserStatus BrowserStatus) {
+	browserStatus.Timestamp = time.Now().Unix()
+	backend.emitEvent(&application.CustomEvent{
+		Name: "BrowserStatus",
+		Data: browserStatus,
+	})
+}
+
+// superviseSession waits for the session's browser to disconnect, stops
+// tracking its tabs and, if options.AutoReconnect is set, reopens the browser
+// with exponential backoff, reporting to the window that opened it. While it
+// waits to reconnect the session stays in Backend.Sessions, so that closing
+// the session (or the app) stops it.
+func (backend *Backend) superviseSession(session *BrowserSession, windowName string, options OpenBrowserOptions) {
+	<-session.disconnected
+	backend.untrackSessionPages(session)
+	reconnect := options.AutoReconnect && !session.closing.Load()
+	if !reconnect {
+		backend.removeSession(session)
+	}
+	backend.emitBrowserStatus(BrowserStatus{
+		ProfileName: session.ProfileName,
+		Status:      "disconnected",
+	})
+	if !reconnect {
+		return
+	}
+	const maxAttempts = 5
+	backoff := time.Second
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		backend.emitBrowserStatus(BrowserStatus{
+			ProfileName: session.ProfileName,
+			Status:      "reconnecting",
+			Message:     fmt.Sprintf("reconnecting in %s", backoff),
+			Attempt:     attempt,
+		})
+		select {
+		case <-time.After(backoff):
+		case <-session.done:
+			return
+		}
+		backoff = min(backoff*2, 30*time.Second)
+		backend.Mutex.Lock()
+		current := backend.Sessions[session.ProfileName]
+		backend.Mutex.Unlock()
+		if current != session {
+			// The session was closed, or someone else opened the profile
+			// in the meantime.
+			return
+		}
+		// openBrowser replaces the session and starts a new supervisor for
+		// the new one.
+		err = backend.openBrowser(context.Background(), windowName, options)
+		if err == nil {
+			if session.closing.Load() {
+				// The session was closed while we were reopening it.
+				backend.CloseBrowser(session.ProfileName)
+			}
+			return
+		}
+	}
+	backend.removeSession(session)
+	backend.emitBrowserStatus(BrowserStatus{
+		ProfileName: session.ProfileName,
+		Status:      "failed",
+		Message:     fmt.Sprintf("giving up after %d attempts: %v", maxAttempts, err),
+		Attempt:     maxAttempts,
+	})
+}
+
+// removeSession removes the session from Backend.Sessions unless it has
+// already been replaced.
+func (backend *Backend) removeSession(session *BrowserSession) {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	if backend.Sessions[session.ProfileName] == session {
+		delete(backend.Sessions, session.ProfileName)
+	}
+}
+
+// BrowserSessionInfo describes an open browser session.
+type BrowserSessionInfo struct {
+	ProfileName string `json:"profileName"`
+	LaunchMode  string `json:"launchMode"`
+	BrowserName string `json:"browserName"`
+	Headless    bool   `json:"headless"`
+	Connected   bool   `json:"connected"`
+}
+
+// ListBrowserSessions returns the open browser sessions, ordered by profile
+// name.
+func (backend *Backend) ListBrowserSessions() []BrowserSessionInfo {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	browserSessions := make([]BrowserSessionInfo, 0, len(backend.Sessions))
+	for _, session := range backend.Sessions {
+		browserSessions = append(browserSessions, BrowserSessionInfo{
+			ProfileName: session.ProfileName,
+			LaunchMode:  session.LaunchMode,
+			BrowserName: session.BrowserName,
+			Headless:    session.Headless,
+			Connected:   session.IsConnected(),
+		})
+	}
+	slices.SortFunc(browserSessions, func(a, b BrowserSessionInfo) int {
+		return strings.Compare(a.ProfileName, b.ProfileName)
+	})
+	return browserSessions
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"changeme/stacktrace"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func init() {
+	application.RegisterEvent[SettingsInfo]("SettingsChanged")
+}
+
+// Settings are the application settings. They are stored in settings.json in
+// the OS config directory (see settingsFile) and can be overridden by
+// environment variables and command line flags, in that order.
+//
+// Empty fields take their default: the directories default to subdirectories
+// of DataDirectory, which itself defaults to ~/browserautomate.
+type Settings struct {
+	// DataDirectory holds the profiles, flows, driver downloads and the
+	// other files of the app.
+	DataDirectory string `json:"dataDirectory"`
+
+	// DriversDirectory holds one subdirectory per Playwright driver version.
+	// PLAYWRIGHT_DRIVER_PATH is not an override for it: as in playwright-go
+	// it names the driver directory itself, see initialize.
+	DriversDirectory string `json:"driversDirectory"`
+
+	// BrowsersDirectory holds the browser builds installed by Playwright.
+	BrowsersDirectory string `json:"browsersDirectory"`
+
+	// ProfilesDirectory holds one Chrome user data directory per profile.
+	ProfilesDirectory string `json:"profilesDirectory"`
+
+	// HTTPAddress is the address of the local HTTP server.
+	HTTPAddress string `json:"httpAddress"`
+
+	// DefaultCDPPort is the remote debugging port for profiles that do not
+	// configure their own. Zero picks a free port.
+	DefaultCDPPort int `json:"defaultCDPPort"`
+
+	// DownloadMirrors are the hosts the driver is downloaded from, tried in
+	// order.
+	DownloadMirrors []string `json:"downloadMirrors"`
+
+	// NetworkSettings are the proxy and CA bundle settings. Their fields are
+	// stored at the top level of settings.json and take effect immediately.
+	NetworkSettings
+}
+
+// settingsSources lists, for each setting (by its JSON name), the
+// environment variable and the command line flag that override it.
+var settingsSources = []struct {
+	Field string
+	Env   string
+	Flag  string
+	Usage string
+}{
+	{"dataDirectory", "BROWSERAUTOMATE_DATA_DIR", "data-dir", "directory for profiles, flows and downloads"},
+	{"driversDirectory", "BROWSERAUTOMATE_DRIVERS_DIR", "drivers-dir", "directory for Playwright drivers, one subdirectory per version"},
+	{"browsersDirectory", "PLAYWRIGHT_BROWSERS_PATH", "browsers-dir", "directory for Playwright browsers"},
+	{"profilesDirectory", "BROWSERAUTOMATE_PROFILES_DIR", "profiles-dir", "directory for Chrome profiles"},
+	{"httpAddress", "BROWSERAUTOMATE_HTTP_ADDRESS", "http-address", "address of the local HTTP server"},
+	{"defaultCDPPort", "BROWSERAUTOMATE_CDP_PORT", "cdp-port", "default remote debugging port (0 picks a free port)"},
+	{"downloadMirrors", "PLAYWRIGHT_DOWNLOAD_HOST", "download-host", "host to download the Playwright driver from"},
+}
+
+// SettingsLayer holds the settings given by environment variables or on the
+// command line, by JSON name. Settings that were not given are missing.
+type SettingsLayer map[string]string
+
+// environmentSettings returns the settings given by environment variables.
+// It must be called before the app sets any of them, e.g.
+// PLAYWRIGHT_BROWSERS_PATH for the driver.
+func environmentSettings() SettingsLayer {
+	settingsEnv := make(SettingsLayer)
+	for _, source := range settingsSources {
+		if s := os.Getenv(source.Env); s != "" {
+			settingsEnv[source.Field] = s
+		}
+	}
+	return settingsEnv
+}
+
+// parseSettingsFlags parses the command line flags for the settings. Other
+// arguments, e.g. the -psn_ argument macOS passes to apps started from the
+// Finder, are logged and ignored.
+func parseSettingsFlags(args []string) (SettingsLayer, error) {
+	flagSet := flag.NewFlagSet("browserautomate", flag.ContinueOnError)
+	values := make(map[string]*string)
+	for _, source := range settingsSources {
+		values[source.Field] = flagSet.String(source.Flag, "", source.Usage)
+	}
+	var settingsArgs []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(args[i], "-"), "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || flagSet.Lookup(name) == nil {
+			slog.Warn("ignoring unknown command line argument", "argument", args[i])
+			continue
+		}
+		settingsArgs = append(settingsArgs, args[i])
+		if !hasValue && i+1 < len(args) {
+			i++
+			settingsArgs = append(settingsArgs, args[i])
+		}
+	}
+	err := flagSet.Parse(settingsArgs)
+	if err != nil {
+		return nil, err
+	}
+	settingsFlags := make(SettingsLayer)
+	flagSet.Visit(func(f *flag.Flag) {
+		for _, source := range settingsSources {
+			if source.Flag == f.Name {
+				settingsFlags[source.Field] = *values[source.Field]
+			}
+		}
+	})
+	return settingsFlags, nil
+}
+
+// SettingsInfo is what the settings window shows: the saved settings, the
+// settings in effect after applying defaults and overrides, and where each
+// override comes from.
+type SettingsInfo struct {
+	Saved     Settings `json:"saved"`
+	Effective Settings `json:"effective"`
+
+	// Overrides maps the JSON name of each overridden setting to the
+	// environment variable or flag that overrides it.
+	Overrides map[string]string `json:"overrides"`
+
+	// RestartRequired is set if a saved directory or the HTTP address
+	// differs from the one in use, these only take effect after a restart.
+	RestartRequired bool `json:"restartRequired"`
+
+	// ProxyPasswordSet is set if a proxy password is saved. The password
+	// itself is never sent out, Saved and Effective have it blanked.
+	ProxyPasswordSet bool `json:"proxyPasswordSet"`
+}
+
+// settingsFile returns the path of settings.json.
+func settingsFile() (string, error) {
+	configDirectory, err := os.UserConfigDir()
+	if err != nil {
+		return "", stacktrace.New(err)
+	}
+	return filepath.Join(configDirectory, "browserautomate", "settings.json"), nil
+}
+
+// SettingsFileError is returned when the saved settings can't be parsed or are
+// invalid. The recovery window offers to reset them, see ResetSettings.
+type SettingsFileError struct {
+	FilePath string
+	Err      error
+}
+
+func (err *SettingsFileError) Error() string {
+	return fmt.Sprintf("%s: %v", err.FilePath, err.Err)
+}
+
+func (err *SettingsFileError) Unwrap() error {
+	return err.Err
+}
+
+func readSettingsFile() (Settings, error) {
+	var settings Settings
+	filePath, err := settingsFile()
+	if err != nil {
+		return settings, err
+	}
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return settings, nil
+		}
+		return settings, stacktrace.New(err)
+	}
+	err = json.Unmarshal(b, &settings)
+	if err != nil {
+		return settings, &SettingsFileError{FilePath: filePath, Err: err}
+	}
+	return settings, nil
+}
+
+func writeSettingsFile(settings Settings) error {
+	filePath, err := settingsFile()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	// The file may contain the proxy password.
+	err = os.WriteFile(filePath, b, 0600)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	err = os.Chmod(filePath, 0600)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// Validate checks the settings. Empty fields are valid, they take their
+// default.
+func (settings Settings) Validate() error {
+	for name, directory := range map[string]string{
+		"data folder":     settings.DataDirectory,
+		"drivers folder":  settings.DriversDirectory,
+		"browsers folder": settings.BrowsersDirectory,
+		"profiles folder": settings.ProfilesDirectory,
+	} {
+		if directory != "" && !filepath.IsAbs(directory) {
+			return fmt.Errorf("%s %q must be an absolute path", name, directory)
+		}
+	}
+	if settings.HTTPAddress != "" {
+		_, portString, err := net.SplitHostPort(settings.HTTPAddress)
+		if err != nil {
+			return fmt.Errorf("invalid HTTP address %q: %w", settings.HTTPAddress, err)
+		}
+		port, err := strconv.Atoi(portString)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid HTTP address %q: invalid port", settings.HTTPAddress)
+		}
+	}
+	if settings.DefaultCDPPort != 0 && (settings.DefaultCDPPort < 1024 || settings.DefaultCDPPort > 65535) {
+		return fmt.Errorf("invalid CDP port %d: must be between 1024 and 65535, or 0 to pick automatically", settings.DefaultCDPPort)
+	}
+	// The CA bundle is only checked when the settings are saved, a missing
+	// file must not keep the app from starting.
+	_, err := settings.proxyURL()
+	if err != nil {
+		return err
+	}
+	for _, mirror := range settings.DownloadMirrors {
+		mirrorURL, err := url.Parse(mirror)
+		if err != nil {
+			return fmt.Errorf("invalid download mirror %q: %w", mirror, err)
+		}
+		if (mirrorURL.Scheme != "http" && mirrorURL.Scheme != "https") || mirrorURL.Host == "" {
+			return fmt.Errorf("invalid download mirror %q: must be an http or https URL", mirror)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns the settings with empty fields set to their defaults.
+func (settings Settings) withDefaults() (Settings, error) {
+	if settings.DataDirectory == "" {
+		userHomeDir, err := os.UserHomeDir()
+		if err != nil {
+			return settings, stacktrace.New(err)
+		}
+		settings.DataDirectory = filepath.Join(userHomeDir, "browserautomate")
+	}
+	if settings.DriversDirectory == "" {
+		settings.DriversDirectory = filepath.Join(settings.DataDirectory, "playwrightdriver")
+	}
+	if settings.BrowsersDirectory == "" {
+		settings.BrowsersDirectory = filepath.Join(settings.DataDirectory, "playwrightbrowsers")
+	}
+	if settings.ProfilesDirectory == "" {
+		settings.ProfilesDirectory = filepath.Join(settings.DataDirectory, "chromeprofiles")
+	}
+	if settings.HTTPAddress == "" {
+		settings.HTTPAddress = "localhost:9246"
+	}
+	if len(settings.DownloadMirrors) == 0 {
+		settings.DownloadMirrors = slices.Clone(playwrightCDNMirrors)
+	}
+	for i, mirror := range settings.DownloadMirrors {
+		settings.DownloadMirrors[i] = strings.TrimSuffix(mirror, "/")
+	}
+	return settings, nil
+}
+
+// withOverrides returns the settings with settingsEnv and then settingsFlags
+// applied, and the source of each override keyed by the setting's JSON name.
+func (settings Settings) withOverrides(settingsEnv, settingsFlags SettingsLayer) (Settings, map[string]string, error) {
+	settings.DownloadMirrors = slices.Clone(settings.DownloadMirrors)
+	overrides := make(map[string]string)
+	for _, source := range settingsSources {
+		s, origin := settingsEnv[source.Field], "environment variable "+source.Env
+		if flagValue, ok := settingsFlags[source.Field]; ok {
+			s, origin = flagValue, "flag -"+source.Flag
+		}
+		if s == "" {
+			continue
+		}
+		switch source.Field {
+		case "dataDirectory":
+			settings.DataDirectory = s
+		case "driversDirectory":
+			settings.DriversDirectory = s
+		case "browsersDirectory":
+			settings.BrowsersDirectory = s
+		case "profilesDirectory":
+			settings.ProfilesDirectory = s
+		case "httpAddress":
+			settings.HTTPAddress = s
+		case "defaultCDPPort":
+			port, err := strconv.Atoi(s)
+			if err != nil {
+				return settings, nil, fmt.Errorf("%s: %q is not a number", origin, s)
+			}
+			settings.DefaultCDPPort = port
+		case "downloadMirrors":
+			settings.DownloadMirrors = strings.Split(s, ",")
+		}
+		overrides[source.Field] = origin
+	}
+	err := settings.Validate()
+	if err != nil {
+		return settings, nil, err
+	}
+	return settings, overrides, nil
+}
+
+// loadSettings reads the saved settings and returns them together with the
+// effective settings and their overrides.
+func loadSettings(settingsEnv, settingsFlags SettingsLayer) (SettingsInfo, error) {
+	var settingsInfo SettingsInfo
+	saved, err := readSettingsFile()
+	if err != nil {
+		return settingsInfo, err
+	}
+	err = saved.Validate()
+	if err != nil {
+		filePath, _ := settingsFile()
+		return settingsInfo, &SettingsFileError{FilePath: filePath, Err: err}
+	}
+	effective, overrides, err := saved.withOverrides(settingsEnv, settingsFlags)
+	if err != nil {
+		return settingsInfo, err
+	}
+	effective, err = effective.withDefaults()
+	if err != nil {
+		return settingsInfo, err
+	}
+	settingsInfo.Saved = saved
+	settingsInfo.Effective = effective
+	settingsInfo.Overrides = overrides
+	return settingsInfo, nil
+}
+
+// settings returns the settings in effect.
+func (backend *Backend) settings() Settings {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	return backend.Settings
+}
+
+// GetSettings returns the saved and the effective settings.
+func (backend *Backend) GetSettings() (SettingsInfo, error) {
+	settingsInfo, err := loadSettings(backend.SettingsEnv, backend.SettingsFlags)
+	if err != nil {
+		return settingsInfo, err
+	}
+	backend.Mutex.Lock()
+	running := backend.Settings
+	backend.Mutex.Unlock()
+	effective := settingsInfo.Effective
+	settingsInfo.RestartRequired = running.DataDirectory != effective.DataDirectory ||
+		running.DriversDirectory != effective.DriversDirectory ||
+		running.BrowsersDirectory != effective.BrowsersDirectory ||
+		running.ProfilesDirectory != effective.ProfilesDirectory ||
+		running.HTTPAddress != effective.HTTPAddress
+	// Show what is actually in use until the app is restarted.
+	settingsInfo.Effective.DataDirectory = running.DataDirectory
+	settingsInfo.Effective.DriversDirectory = running.DriversDirectory
+	settingsInfo.Effective.BrowsersDirectory = running.BrowsersDirectory
+	settingsInfo.Effective.ProfilesDirectory = running.ProfilesDirectory
+	settingsInfo.Effective.HTTPAddress = running.HTTPAddress
+	// The settings go out to every window, /events subscriber and REST
+	// client, keep the proxy password to ourselves.
+	settingsInfo.ProxyPasswordSet = settingsInfo.Saved.ProxyPassword != ""
+	settingsInfo.Saved.ProxyPassword = ""
+	settingsInfo.Effective.ProxyPassword = ""
+	return settingsInfo, nil
+}
+
+// withSavedProxyPassword returns the network settings with the saved proxy
+// password filled in if none was given. GetSettings never sends the password
+// out, so an empty one means "unchanged" as long as there is a username.
+func (settings NetworkSettings) withSavedProxyPassword() NetworkSettings {
+	if settings.ProxyPassword != "" || settings.ProxyUsername == "" {
+		return settings
+	}
+	saved, err := readSettingsFile()
+	if err != nil {
+		return settings
+	}
+	settings.ProxyPassword = saved.ProxyPassword
+	return settings
+}
+
+// UpdateSettings validates and saves the settings and broadcasts a
+// SettingsChanged event. The CDP port, download mirrors and network settings
+// take effect immediately, the directories and the HTTP address after a restart.
+// An empty proxy password keeps the saved one, unless the username is empty.
+func (backend *Backend) UpdateSettings(settings Settings) error {
+	settings.NetworkSettings = settings.NetworkSettings.withSavedProxyPassword()
+	err := settings.Validate()
+	if err != nil {
+		return err
+	}
+	err = settings.NetworkSettings.Validate()
+	if err != nil {
+		return err
+	}
+	err = writeSettingsFile(settings)
+	if err != nil {
+		return err
+	}
+	settingsInfo, err := loadSettings(backend.SettingsEnv, backend.SettingsFlags)
+	if err != nil {
+		return err
+	}
+	backend.Mutex.Lock()
+	backend.Settings.DefaultCDPPort = settingsInfo.Effective.DefaultCDPPort
+	backend.Settings.DownloadMirrors = settingsInfo.Effective.DownloadMirrors
+	backend.Settings.NetworkSettings = settingsInfo.Effective.NetworkSettings
+	backend.Mutex.Unlock()
+	settingsInfo, err = backend.GetSettings()
+	if err != nil {
+		return err
+	}
+	backend.emitEvent(&application.CustomEvent{
+		Name: "SettingsChanged",
+		Data: settingsInfo,
+	})
+	return nil
+}
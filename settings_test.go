@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGetSettingsRedactsProxyPassword(t *testing.T) {
+	configDirectory := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDirectory)
+	t.Setenv("HOME", configDirectory)
+	t.Setenv("AppData", configDirectory)
+	err := writeSettingsFile(Settings{
+		NetworkSettings: NetworkSettings{
+			ProxyURL:      "http://proxy.example:3128",
+			ProxyUsername: "user",
+			ProxyPassword: "secret",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &Backend{}
+	settingsInfo, err := backend.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settingsInfo.Saved.ProxyPassword != "" || settingsInfo.Effective.ProxyPassword != "" {
+		t.Errorf("got proxy password %q/%q, want it redacted", settingsInfo.Saved.ProxyPassword, settingsInfo.Effective.ProxyPassword)
+	}
+	if !settingsInfo.ProxyPasswordSet {
+		t.Error("got proxyPasswordSet false, want true")
+	}
+
+	// Submitting the redacted settings keeps the saved password.
+	networkSettings := settingsInfo.Saved.NetworkSettings.withSavedProxyPassword()
+	if networkSettings.ProxyPassword != "secret" {
+		t.Errorf("got proxy password %q, want the saved one", networkSettings.ProxyPassword)
+	}
+	networkSettings = NetworkSettings{ProxyUsername: "user", ProxyPassword: "new"}.withSavedProxyPassword()
+	if networkSettings.ProxyPassword != "new" {
+		t.Errorf("got proxy password %q, want the new one", networkSettings.ProxyPassword)
+	}
+	networkSettings = NetworkSettings{}.withSavedProxyPassword()
+	if networkSettings.ProxyPassword != "" {
+		t.Errorf("got proxy password %q without a username, want none", networkSettings.ProxyPassword)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"changeme/stacktrace"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func init() {
+	application.RegisterEvent[StartupStatus]("StartupStatus")
+}
+
+// The backend starts in StartupStateStarting and moves to StartupStateReady
+// once initialize succeeds. If it fails the backend is in StartupStateFailed
+// and the recovery window is shown instead of the main window until
+// RetryStartup succeeds.
+const (
+	StartupStateStarting = "starting"
+	StartupStateReady    = "ready"
+	StartupStateFailed   = "failed"
+)
+
+// StartupStatus is the state of the backend's initialisation.
+type StartupStatus struct {
+	State            string `json:"state"`
+	Error            string `json:"error"`
+	DriversDirectory string `json:"driversDirectory"`
+
+	// SettingsInvalid is set if startup failed because the saved settings
+	// are invalid, ResetSettings fixes that.
+	SettingsInvalid bool `json:"settingsInvalid"`
+}
+
+// initialize sets up the backend's directories and the Playwright driver
+// according to the settings. If driversDirectory is not empty it is used
+// instead of the drivers directory from the settings. It can be called again
+// after it failed.
+func (backend *Backend) initialize(driversDirectory string) error {
+	settingsInfo, err := loadSettings(backend.SettingsEnv, backend.SettingsFlags)
+	if err != nil {
+		return err
+	}
+	settings := settingsInfo.Effective
+	if driversDirectory != "" {
+		settings.DriversDirectory = driversDirectory
+	}
+	backend.Mutex.Lock()
+	backend.Settings = settings
+	backend.Mutex.Unlock()
+	controlToken, err := loadControlToken(settings.DataDirectory)
+	if err != nil {
+		return fmt.Errorf("loading the control API token: %w", err)
+	}
+	backend.Mutex.Lock()
+	backend.ControlToken = controlToken
+	backend.Mutex.Unlock()
+	backend.DriversDirectory = settings.DriversDirectory
+	err = os.MkdirAll(settings.DriversDirectory, 0777)
+	if err != nil {
+		return fmt.Errorf("creating drivers folder: %w", err)
+	}
+	// Both the driver and findPlaywrightChromium look for the browser
+	// builds in PLAYWRIGHT_BROWSERS_PATH, findPlaywrightChromium also in the
+	// shared ms-playwright cache.
+	err = os.Setenv("PLAYWRIGHT_BROWSERS_PATH", settings.BrowsersDirectory)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	err = os.MkdirAll(settings.ProfilesDirectory, 0777)
+	if err != nil {
+		return fmt.Errorf("creating profiles folder: %w", err)
+	}
+	// Earlier versions only had a single profile, migrate it over to
+	// become the default profile.
+	defaultProfileDirectory := filepath.Join(settings.ProfilesDirectory, DefaultProfileName)
+	_, err = os.Stat(defaultProfileDirectory)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return stacktrace.New(err)
+		}
+		legacyProfileDirectory := filepath.Join(settings.DataDirectory, "chromeprofile")
+		err = os.Rename(legacyProfileDirectory, defaultProfileDirectory)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return stacktrace.New(err)
+		}
+	}
+	err = os.MkdirAll(defaultProfileDirectory, 0777)
+	if err != nil {
+		return fmt.Errorf("creating profiles folder: %w", err)
+	}
+	backend.ProfilesDirectory = settings.ProfilesDirectory
+	backend.BrowserLauncherFile = filepath.Join(settings.DataDirectory, "browserlauncher.json")
+	backend.FlowsDirectory = filepath.Join(settings.DataDirectory, "flows")
+	backend.DriverChecksumsFile = filepath.Join(settings.DataDirectory, "driverchecksums.json")
+	backend.DownloadsDirectory = filepath.Join(settings.DataDirectory, "playwrightdownloads")
+	// PLAYWRIGHT_DRIVER_PATH is the directory of a single driver managed by
+	// the user, as in playwright-go, rather than one of our versioned
+	// subdirectories. It is neither migrated nor cleaned up.
+	driverPath := os.Getenv("PLAYWRIGHT_DRIVER_PATH")
+	if driverPath == "" {
+		err = migrateDriverDirectory(settings.DriversDirectory, backend.DownloadsDirectory)
+		if err != nil {
+			return fmt.Errorf("migrating drivers folder: %w", err)
+		}
+	}
+	playwrightRunOptions := &playwright.RunOptions{
+		DriverDirectory:     settings.DriversDirectory,
+		SkipInstallBrowsers: true,
+		Verbose:             true,
+	}
+	playwrightDriver, err := playwright.NewDriver(playwrightRunOptions)
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	// The driver keeps a pointer to playwrightRunOptions, point it at the
+	// subdirectory for its version.
+	playwrightRunOptions.DriverDirectory = filepath.Join(settings.DriversDirectory, playwrightDriver.Version)
+	if driverPath != "" {
+		playwrightRunOptions.DriverDirectory = driverPath
+	}
+	backend.PlaywrightDriver = playwrightDriver
+	backend.PlaywrightRunOptions = playwrightRunOptions
+	return nil
+}
+
+// start initialises the backend and opens the main window, or the recovery
+// window if initialisation fails.
+func (backend *Backend) start() {
+	err := backend.initialize("")
+	if err != nil {
+		backend.setStartupStatus(StartupStateFailed, err)
+		backend.App.Window.NewWithOptions(application.WebviewWindowOptions{
+			Name:   "recovery",
+			Title:  "Browser Automate",
+			Width:  720,
+			Height: 420,
+			URL:    "/recovery.html",
+		})
+		return
+	}
+	backend.setStartupStatus(StartupStateReady, nil)
+	backend.openMainWindow()
+}
+
+// openMainWindow opens the main window.
+func (backend *Backend) openMainWindow() {
+	window := backend.App.Window.NewWithOptions(application.WebviewWindowOptions{
+		Title: "Browser Automate",
+		Mac: application.MacWindow{
+			InvisibleTitleBarHeight: 50,
+			Backdrop:                application.MacBackdropTranslucent,
+			TitleBar:                application.MacTitleBarHiddenInset,
+		},
+		BackgroundColour: application.NewRGB(27, 38, 54),
+		URL:              "/index.html?foo=bar&foo=baz",
+	})
+	backend.Mutex.Lock()
+	backend.Windows["index"] = window
+	backend.Mutex.Unlock()
+}
+
+// setStartupStatus records the startup state and broadcasts it as a
+// StartupStatus event.
+func (backend *Backend) setStartupStatus(state string, err error) {
+	backend.Mutex.Lock()
+	backend.Startup = StartupStatus{
+		State:            state,
+		DriversDirectory: backend.DriversDirectory,
+	}
+	if err != nil {
+		backend.Startup.Error = err.Error()
+		var settingsFileErr *SettingsFileError
+		backend.Startup.SettingsInvalid = errors.As(err, &settingsFileErr)
+	}
+	startupStatus := backend.Startup
+	backend.Mutex.Unlock()
+	backend.emitEvent(&application.CustomEvent{
+		Name: "StartupStatus",
+		Data: startupStatus,
+	})
+}
+
+// ready reports whether the backend has been initialised. Until it has, the
+// driver and the directories it uses are not set.
+func (backend *Backend) ready() bool {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	return backend.Startup.State == StartupStateReady
+}
+
+// StartupStatus returns the state of the backend's initialisation.
+func (backend *Backend) StartupStatus() StartupStatus {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	return backend.Startup
+}
+
+// RetryStartup initialises the backend again after a failed start. If
+// driversDirectory is not empty it is used as the drivers directory and saved
+// in the settings. On success the recovery window is replaced by the main
+// window.
+func (backend *Backend) RetryStartup(driversDirectory string) error {
+	backend.Mutex.Lock()
+	state := backend.Startup.State
+	if state == StartupStateFailed {
+		backend.Startup.State = StartupStateStarting
+	}
+	backend.Mutex.Unlock()
+	switch state {
+	case StartupStateReady:
+		return nil
+	case StartupStateStarting:
+		return fmt.Errorf("startup is already in progress")
+	}
+	backend.setStartupStatus(StartupStateStarting, nil)
+	err := backend.initialize(driversDirectory)
+	if err != nil {
+		backend.setStartupStatus(StartupStateFailed, err)
+		return err
+	}
+	if driversDirectory != "" {
+		settings, err := readSettingsFile()
+		if err != nil {
+			backend.setStartupStatus(StartupStateFailed, err)
+			return err
+		}
+		settings.DriversDirectory = driversDirectory
+		err = writeSettingsFile(settings)
+		if err != nil {
+			backend.setStartupStatus(StartupStateFailed, err)
+			return err
+		}
+	}
+	backend.setStartupStatus(StartupStateReady, nil)
+	backend.openMainWindow()
+	if window, ok := backend.App.Window.GetByName("recovery"); ok {
+		window.Close()
+	}
+	return nil
+}
+
+// ResetSettings moves the saved settings aside to settings.json.invalid after a
+// failed start, so that the next RetryStartup uses the defaults.
+func (backend *Backend) ResetSettings() error {
+	backend.Mutex.Lock()
+	state := backend.Startup.State
+	backend.Mutex.Unlock()
+	if state != StartupStateFailed {
+		return fmt.Errorf("settings can only be reset after a failed start")
+	}
+	filePath, err := settingsFile()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(filePath, filePath+".invalid")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// ChooseDirectory asks the user to pick a directory and returns its path, or
+// an empty string if the dialog was cancelled.
+func (backend *Backend) ChooseDirectory(title string, directory string) (string, error) {
+	return backend.App.Dialog.OpenFile().
+		SetTitle(title).
+		SetDirectory(directory).
+		CanChooseFiles(false).
+		CanChooseDirectories(true).
+		CanCreateDirectories(true).
+		PromptForSingleSelection()
+}
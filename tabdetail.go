@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func init() {
+	application.RegisterEvent[TabDetail]("TabDetailUpdated")
+}
+
+// TabDetail is the live state of a tab, emitted to all windows as
+// TabDetailUpdated whenever it changes.
+type TabDetail struct {
+	TabID               int64  `json:"tabID"`
+	ProfileName         string `json:"profileName"`
+	URL                 string `json:"url"`
+	Title               string `json:"title"`
+	FaviconURL          string `json:"faviconURL"` // data URL
+	LoadState           string `json:"loadState"`  // loading|domcontentloaded|load
+	ConsoleMessageCount int    `json:"consoleMessageCount"`
+	LastError           string `json:"lastError"`
+	Timestamp           int64  `json:"timestamp"`
+}
+
+// maxFaviconSize is the largest favicon we will inline as a data URL.
+const maxFaviconSize = 64 * 1000
+
+// inspectPage keeps Backend.TabDetails up to date for a tracked page. Console
+// message counts and errors are reset whenever the page navigates.
+func (backend *Backend) inspectPage(session *BrowserSession, pageID int64, page playwright.Page) {
+	backend.Mutex.Lock()
+	backend.TabDetails[pageID] = &TabDetail{
+		TabID:       pageID,
+		ProfileName: session.ProfileName,
+		URL:         page.URL(),
+		LoadState:   "load",
+	}
+	backend.Mutex.Unlock()
+	page.OnFrameNavigated(func(frame playwright.Frame) {
+		if frame != page.MainFrame() {
+			return
+		}
+		backend.updateTabDetail(pageID, func(tabDetail *TabDetail) {
+			tabDetail.URL = frame.URL()
+			tabDetail.Title = ""
+			tabDetail.FaviconURL = ""
+			tabDetail.LoadState = "loading"
+			tabDetail.ConsoleMessageCount = 0
+			tabDetail.LastError = ""
+		})
+	})
+	page.OnDOMContentLoaded(func(playwright.Page) {
+		backend.updateTabDetail(pageID, func(tabDetail *TabDetail) {
+			tabDetail.LoadState = "domcontentloaded"
+		})
+	})
+	page.OnLoad(func(playwright.Page) {
+		backend.updateTabDetail(pageID, func(tabDetail *TabDetail) {
+			tabDetail.LoadState = "load"
+		})
+		// Event handlers run on Playwright's dispatcher goroutine, calls
+		// that talk to the browser must not block it.
+		go backend.refreshTabDetail(pageID, page)
+	})
+	page.OnConsole(func(consoleMessage playwright.ConsoleMessage) {
+		backend.updateTabDetail(pageID, func(tabDetail *TabDetail) {
+			tabDetail.ConsoleMessageCount++
+			if consoleMessage.Type() == "error" {
+				tabDetail.LastError = consoleMessage.Text()
+			}
+		})
+	})
+	page.OnPageError(func(err error) {
+		backend.updateTabDetail(pageID, func(tabDetail *TabDetail) {
+			tabDetail.LastError = err.Error()
+		})
+	})
+	go backend.refreshTabDetail(pageID, page)
+}
+
+// updateTabDetail applies update to a tab's detail and emits
+// TabDetailUpdated.
+func (backend *Backend) updateTabDetail(pageID int64, update func(tabDetail *TabDetail)) {
+	backend.Mutex.Lock()
+	tabDetail, ok := backend.TabDetails[pageID]
+	if !ok {
+		backend.Mutex.Unlock()
+		return
+	}
+	update(tabDetail)
+	tabDetail.Timestamp = time.Now().Unix()
+	data := *tabDetail
+	backend.Mutex.Unlock()
+	backend.emitEvent(&application.CustomEvent{
+		Name: "TabDetailUpdated",
+		Data: data,
+	})
+}
+
+// refreshTabDetail fetches the title and favicon of a page.
+func (backend *Backend) refreshTabDetail(pageID int64, page playwright.Page) {
+	pageURL := page.URL()
+	title, _ := page.Title()
+	faviconURL, _ := fetchFavicon(page)
+	backend.updateTabDetail(pageID, func(tabDetail *TabDetail) {
+		if tabDetail.URL != pageURL {
+			// The page navigated away in the meantime.
+			return
+		}
+		tabDetail.Title = title
+		tabDetail.FaviconURL = faviconURL
+	})
+}
+
+// fetchFavicon downloads the favicon of a page (using the page's cookies) and
+// returns it as a data URL.
+func fetchFavicon(page playwright.Page) (string, error) {
+	pageURL, err := url.Parse(page.URL())
+	if err != nil {
+		return "", err
+	}
+	if pageURL.Scheme != "http" && pageURL.Scheme != "https" {
+		return "", nil
+	}
+	href, err := page.Evaluate(`() => {
+		const link = document.querySelector("link[rel~='icon']");
+		return link ? link.href : "";
+	}`)
+	if err != nil {
+		return "", err
+	}
+	faviconURL, _ := href.(string)
+	if faviconURL == "" {
+		faviconURL = pageURL.Scheme + "://" + pageURL.Host + "/favicon.ico"
+	}
+	if strings.HasPrefix(faviconURL, "data:") {
+		return faviconURL, nil
+	}
+	response, err := page.Context().Request().Get(faviconURL)
+	if err != nil {
+		return "", err
+	}
+	defer response.Dispose()
+	if !response.Ok() {
+		return "", fmt.Errorf("GET %s: %d %s", faviconURL, response.Status(), response.StatusText())
+	}
+	body, err := response.Body()
+	if err != nil {
+		return "", err
+	}
+	if len(body) == 0 || len(body) > maxFaviconSize {
+		return "", nil
+	}
+	contentType, _, _ := mime.ParseMediaType(response.Headers()["content-type"])
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "image/x-icon"
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(body), nil
+}
+
+// TabDetail returns the live state of a tab.
+func (backend *Backend) TabDetail(tabID int64) (TabDetail, error) {
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	tabDetail, ok := backend.TabDetails[tabID]
+	if !ok {
+		return TabDetail{}, fmt.Errorf("no such tab: %d", tabID)
+	}
+	return *tabDetail, nil
+}
+
+// ListTabDetails returns the live state of all tabs, in the order they were
+// opened.
+func (backend *Backend) ListTabDetails() []TabDetail {
+	tabs := backend.ListTabs()
+	backend.Mutex.Lock()
+	defer backend.Mutex.Unlock()
+	tabDetails := make([]TabDetail, 0, len(tabs))
+	for _, tab := range tabs {
+		if tabDetail, ok := backend.TabDetails[tab.TabID]; ok {
+			tabDetails = append(tabDetails, *tabDetail)
+		}
+	}
+	return tabDetails
+}
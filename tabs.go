@@ -0,0 +1,227 @@
+package main
+
+import (
+	"changeme/stacktrace"
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func init() {
+	application.RegisterEvent[TabEvent]("TabOpened")
+	application.RegisterEvent[TabEvent]("TabClosed")
+	application.RegisterEvent[TabEvent]("TabNavigated")
+}
+
+// Tab is a page open in one of the profiles' browsers.
+type Tab struct {
+	TabID       int64  `json:"tabID"`
+	ProfileName string `json:"profileName"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+}
+
+// TabEvent is emitted to all windows as TabOpened, TabClosed or TabNavigated.
+type TabEvent struct {
+	Tab       Tab   `json:"tab"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+func (backend *Backend) emitTabEvent(name string, tab Tab) {
+	backend.emitEvent(&application.CustomEvent{
+		Name: name,
+		Data: TabEvent{
+			Tab:       tab,
+			Timestamp: time.Now().Unix(),
+		},
+	})
+}
+
+// trackSessionPages assigns IDs to the pages currently open in the session,
+// adds them to Backend.Pages and keeps Backend.Pages up to date as pages are
+// opened and closed.
+//
+// Playwright does not tell us when browser contexts are created or closed, so
+// we only ever watch the contexts that exist when the session connects.
+func (backend *Backend) trackSessionPages(session *BrowserSession) {
+	for _, browserContext := range session.Contexts() {
+		browserContext.OnPage(func(page playwright.Page) {
+			backend.trackPage(session, page)
+		})
+		for _, page := range browserContext.Pages() {
+			backend.trackPage(session, page)
+		}
+	}
+}
+
+// trackPage adds a page to Backend.Pages under a new ID from
+// Backend.Sequence. A page that is already tracked keeps its ID.
+func (backend *Backend) trackPage(session *BrowserSession, page playwright.Page) int64 {
+	backend.Mutex.Lock()
+	// A page opened while trackSessionPages registers OnPage and lists the
+	// existing pages is reported by both.
+	for pageID := range session.PageIDs {
+		if backend.Pages[pageID] == page {
+			backend.Mutex.Unlock()
+			return pageID
+		}
+	}
+	pageID := backend.Sequence.Add(1)
+	backend.Pages[pageID] = page
+	session.PageIDs[pageID] = struct{}{}
+	backend.Mutex.Unlock()
+	page.OnClose(func(playwright.Page) {
+		backend.Mutex.Lock()
+		_, ok := backend.Pages[pageID]
+		delete(backend.Pages, pageID)
+		delete(backend.TabDetails, pageID)
+		delete(session.PageIDs, pageID)
+		backend.Mutex.Unlock()
+		if ok {
+			backend.emitTabEvent("TabClosed", Tab{TabID: pageID, ProfileName: session.ProfileName, URL: page.URL()})
+		}
+	})
+	page.OnFrameNavigated(func(frame playwright.Frame) {
+		if frame != page.MainFrame() {
+			return
+		}
+		// Event handlers run on Playwright's dispatcher goroutine, calls
+		// that talk to the browser (like Title) must not block it.
+		go func() {
+			backend.emitTabEvent("TabNavigated", backend.tab(session.ProfileName, pageID, page))
+		}()
+	})
+	backend.inspectPage(session, pageID, page)
+	go func() {
+		backend.emitTabEvent("TabOpened", backend.tab(session.ProfileName, pageID, page))
+	}()
+	return pageID
+}
+
+// untrackSessionPages removes all of the session's pages from Backend.Pages.
+func (backend *Backend) untrackSessionPages(session *BrowserSession) {
+	backend.Mutex.Lock()
+	pageIDs := make([]int64, 0, len(session.PageIDs))
+	for pageID := range session.PageIDs {
+		delete(backend.Pages, pageID)
+		delete(backend.TabDetails, pageID)
+		pageIDs = append(pageIDs, pageID)
+	}
+	clear(session.PageIDs)
+	backend.Mutex.Unlock()
+	for _, pageID := range pageIDs {
+		backend.emitTabEvent("TabClosed", Tab{TabID: pageID, ProfileName: session.ProfileName})
+	}
+}
+
+func (backend *Backend) tab(profileName string, pageID int64, page playwright.Page) Tab {
+	// Title fails if the page is closed or navigating, the URL is enough to
+	// go on.
+	title, _ := page.Title()
+	return Tab{
+		TabID:       pageID,
+		ProfileName: profileName,
+		URL:         page.URL(),
+		Title:       title,
+	}
+}
+
+// page returns the tracked page for a tab ID.
+func (backend *Backend) page(tabID int64) (playwright.Page, error) {
+	backend.Mutex.Lock()
+	page, ok := backend.Pages[tabID]
+	backend.Mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no such tab: %d", tabID)
+	}
+	return page, nil
+}
+
+// ListTabs returns the tabs open in all connected browsers, in the order they
+// were opened.
+func (backend *Backend) ListTabs() []Tab {
+	type entry struct {
+		profileName string
+		pageID      int64
+		page        playwright.Page
+	}
+	var entries []entry
+	backend.Mutex.Lock()
+	for profileName, session := range backend.Sessions {
+		for pageID := range session.PageIDs {
+			entries = append(entries, entry{profileName: profileName, pageID: pageID, page: backend.Pages[pageID]})
+		}
+	}
+	backend.Mutex.Unlock()
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.pageID, b.pageID)
+	})
+	tabs := make([]Tab, 0, len(entries))
+	for _, entry := range entries {
+		tabs = append(tabs, backend.tab(entry.profileName, entry.pageID, entry.page))
+	}
+	return tabs
+}
+
+// ActivateTab brings a tab to the front of its browser window.
+func (backend *Backend) ActivateTab(tabID int64) error {
+	page, err := backend.page(tabID)
+	if err != nil {
+		return err
+	}
+	err = page.BringToFront()
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// CloseTab closes a tab.
+func (backend *Backend) CloseTab(tabID int64) error {
+	page, err := backend.page(tabID)
+	if err != nil {
+		return err
+	}
+	err = page.Close()
+	if err != nil {
+		return stacktrace.New(err)
+	}
+	return nil
+}
+
+// NewTab opens a new tab in a profile's browser and navigates it to url (if
+// not empty).
+func (backend *Backend) NewTab(profileName string, url string) (Tab, error) {
+	if profileName == "" {
+		profileName = DefaultProfileName
+	}
+	backend.Mutex.Lock()
+	session := backend.Sessions[profileName]
+	backend.Mutex.Unlock()
+	if !session.IsConnected() {
+		return Tab{}, fmt.Errorf("profile %q is not open", profileName)
+	}
+	browserContexts := session.Contexts()
+	if len(browserContexts) == 0 {
+		return Tab{}, fmt.Errorf("profile %q has no browser context", profileName)
+	}
+	page, err := browserContexts[0].NewPage()
+	if err != nil {
+		return Tab{}, stacktrace.New(err)
+	}
+	if url != "" {
+		_, err = page.Goto(url)
+		if err != nil {
+			page.Close()
+			return Tab{}, fmt.Errorf("navigating to %s: %w", url, err)
+		}
+	}
+	// The OnPage handler may have tracked the page already, trackPage
+	// returns its existing ID then.
+	pageID := backend.trackPage(session, page)
+	return Tab{TabID: pageID, ProfileName: profileName, URL: page.URL()}, nil
+}